@@ -0,0 +1,202 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+
+	"github.com/denis101/monzo-techtest/parser"
+	"github.com/hashicorp/go-hclog"
+)
+
+type checkpoint struct {
+	Visited   []string            `json:"visited"`
+	Frontier  []string            `json:"frontier"`
+	Results   []Result            `json:"results"`
+	Graph     map[string][]string `json:"graph,omitempty"`
+	Deferred  map[string]int      `json:"deferred,omitempty"`
+	Referrers map[string]string   `json:"referrers,omitempty"`
+	Hashes    map[string]string   `json:"hashes,omitempty"`
+	Depths    map[string]int      `json:"depths,omitempty"`
+}
+
+func (c *Crawler) SaveState(path string) error {
+	visited := c.visited.Slice()
+	done := make(map[string]bool, len(visited))
+	for _, v := range visited {
+		done[v] = true
+	}
+
+	var frontier []string
+	for _, link := range c.cache.Slice() {
+		if !done[link] {
+			frontier = append(frontier, link)
+		}
+	}
+
+	c.resultLock.Lock()
+	var results []Result
+	for _, r := range c.result {
+		if done[r.URL] {
+			results = append(results, r)
+		}
+	}
+	c.resultLock.Unlock()
+
+	c.graphLock.Lock()
+	deferred := make(map[string]int, len(c.deferred))
+	for url, t := range c.deferred {
+		deferred[url] = t.depth
+	}
+	c.graphLock.Unlock()
+
+	c.referrerLock.Lock()
+	referrers := maps.Clone(c.referrers)
+	c.referrerLock.Unlock()
+
+	c.hashLock.Lock()
+	hashes := maps.Clone(c.hashes)
+	c.hashLock.Unlock()
+
+	c.depthLock.Lock()
+	depths := maps.Clone(c.discovered)
+	c.depthLock.Unlock()
+
+	b, err := json.Marshal(checkpoint{
+		Visited:   visited,
+		Frontier:  frontier,
+		Results:   results,
+		Graph:     c.graph.snapshot(),
+		Deferred:  deferred,
+		Referrers: referrers,
+		Hashes:    hashes,
+		Depths:    depths,
+	})
+	if err != nil {
+		return err
+	}
+
+	return parser.WriteFileAtomic(path, b)
+}
+
+func (c *Crawler) LoadState(path string) error {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		hclog.Default().Info("no checkpoint found, starting fresh", "filename", path)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var state checkpoint
+	if err := json.Unmarshal(b, &state); err != nil {
+		return err
+	}
+
+	c.visited.AddSlice(state.Visited)
+	c.claimed.AddSlice(state.Visited)
+	c.fetched.Store(int32(len(state.Visited)))
+	c.cache.AddSlice(state.Visited)
+	c.cache.AddSlice(state.Frontier)
+
+	c.resultLock.Lock()
+	c.result = state.Results
+	for _, r := range state.Results {
+		c.depths[r.Depth]++
+		if !r.Asset {
+			c.recorded[r.URL] = r.Depth
+			c.countInbound(r)
+		}
+		c.statuses[r.Status]++
+		if isBroken(r) {
+			c.broken = append(c.broken, r)
+		}
+	}
+	c.resultLock.Unlock()
+
+	if c.opts.CheckInternalLinks {
+		for _, r := range state.Results {
+			c.addInbound(r.URL, r.Links)
+		}
+	}
+
+	c.graph.restore(state.Graph)
+	c.graphLock.Lock()
+	for url, depth := range state.Deferred {
+		c.deferred[url] = crawlTask{url: url, depth: depth, referrer: state.Referrers[url]}
+	}
+	c.graphLock.Unlock()
+
+	c.referrerLock.Lock()
+	maps.Copy(c.referrers, state.Referrers)
+	c.referrerLock.Unlock()
+
+	c.hashLock.Lock()
+	maps.Copy(c.hashes, state.Hashes)
+	c.hashLock.Unlock()
+
+	c.depthLock.Lock()
+	maps.Copy(c.discovered, state.Depths)
+	c.depthLock.Unlock()
+
+	hclog.Default().Info("resumed from checkpoint",
+		"filename", path,
+		"visited", len(state.Visited),
+		"frontier", len(state.Frontier),
+	)
+	return nil
+}
+
+func (c *Crawler) frontier() []string {
+	var frontier []string
+	for _, link := range c.cache.Slice() {
+		if !c.visited.Has(link) {
+			frontier = append(frontier, link)
+		}
+	}
+	return frontier
+}
+
+func (c *Crawler) frontierTasks() []crawlTask {
+	frontier := c.frontier()
+	if len(frontier) <= 0 {
+		return nil
+	}
+
+	depths := make(map[string]int)
+	c.resultLock.Lock()
+	for _, r := range c.result {
+		for _, l := range r.Links {
+			if d, ok := depths[l]; !ok || r.Depth+1 < d {
+				depths[l] = r.Depth + 1
+			}
+		}
+	}
+	c.resultLock.Unlock()
+
+	c.depthLock.Lock()
+	for url, d := range c.discovered {
+		if existing, ok := depths[url]; !ok || d < existing {
+			depths[url] = d
+		}
+	}
+	c.depthLock.Unlock()
+
+	tasks := make([]crawlTask, len(frontier))
+	for i, l := range frontier {
+		tasks[i] = crawlTask{url: l, depth: depths[l], referrer: c.referrerOf(l)}
+	}
+	return tasks
+}
+
+func (c *Crawler) checkpoint() {
+	if err := c.SaveState(c.opts.CheckpointFile); err != nil {
+		hclog.Default().Error("failed to write checkpoint", "filename", c.opts.CheckpointFile, "error", err)
+		return
+	}
+
+	hclog.Default().Trace("wrote checkpoint", "filename", c.opts.CheckpointFile)
+}
@@ -0,0 +1,217 @@
+package crawler
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.ckpt")
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.cache.AddSlice([]string{"https://monzo.com", "https://monzo.com/about", "https://monzo.com/blog"})
+	c.visited.Add("https://monzo.com")
+	c.result = []Result{{URL: "https://monzo.com", Status: 200, Count: 2}}
+
+	if err := c.SaveState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	if err := resumed.LoadState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	frontier := resumed.frontier()
+	slices.Sort(frontier)
+	expected := []string{"https://monzo.com/about", "https://monzo.com/blog"}
+	if !slices.Equal(frontier, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, frontier)
+	}
+
+	if !resumed.visited.Has("https://monzo.com") {
+		t.Fatal("expected seed to be visited")
+	}
+
+	if len(resumed.result) != 1 || resumed.result[0].URL != "https://monzo.com" {
+		t.Fatalf("unexpected results: %v", resumed.result)
+	}
+}
+
+func TestSaveStateAfterPartialCrawl(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/about">about</a><a href="/blog">blog</a>`,
+		"/about": `<p>about</p>`,
+		"/blog":  `<p>blog</p>`,
+	})
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "crawl.ckpt")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, MaxPages: 1})
+	c.Crawl(server.URL)
+	if err := c.SaveState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	if err := resumed.LoadState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	frontier := resumed.frontier()
+	slices.Sort(frontier)
+	expected := []string{server.URL + "/about", server.URL + "/blog"}
+	if !slices.Equal(frontier, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, frontier)
+	}
+
+	if resumed.Stats().StatusCodes[http.StatusOK] != 1 {
+		t.Fatalf("expected: %d, actual: %d", 1, resumed.Stats().StatusCodes[http.StatusOK])
+	}
+}
+
+func TestLoadStateMissingFileStartsFresh(t *testing.T) {
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	if err := c.LoadState(filepath.Join(t.TempDir(), "missing.ckpt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.cache.Size() != 0 || c.visited.Size() != 0 {
+		t.Fatal("expected empty state")
+	}
+}
+
+func TestCrawlResumesFrontierOnly(t *testing.T) {
+	var lock sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requested = append(requested, r.URL.Path)
+		lock.Unlock()
+		w.Write([]byte(`<a href="/">home</a><a href="/about">about</a><a href="/blog">blog</a>`))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "crawl.ckpt")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.cache.AddSlice([]string{server.URL, server.URL + "/about", server.URL + "/blog"})
+	c.visited.AddSlice([]string{server.URL, server.URL + "/about"})
+	c.result = []Result{{URL: server.URL, Status: 200}, {URL: server.URL + "/about", Status: 200}}
+	if err := c.SaveState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := getTestCrawler(CrawlerOptions{MaxWorkers: 1, ResumeFile: filename, CheckpointFile: filename})
+	resumed.Crawl(server.URL)
+
+	if !slices.Equal(requested, []string{"/blog"}) {
+		t.Fatalf("expected only frontier to be requested, actual: %v", requested)
+	}
+
+	if len(resumed.result) != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, len(resumed.result))
+	}
+
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	final := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	if err := final.LoadState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(final.frontier()) != 0 {
+		t.Fatalf("expected empty frontier after completed crawl, actual: %v", final.frontier())
+	}
+}
+
+func TestSaveStateOnlyKeepsCompletedPages(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.ckpt")
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.cache.AddSlice([]string{"https://monzo.com", "https://monzo.com/about"})
+	c.visited.Add("https://monzo.com")
+	c.claimed.AddSlice([]string{"https://monzo.com", "https://monzo.com/about"})
+	c.result = []Result{{URL: "https://monzo.com"}, {URL: "https://monzo.com/about"}}
+	c.referrers["https://monzo.com/about"] = "https://monzo.com"
+	c.hashes["abc"] = "https://monzo.com"
+	c.graph.addEdges("https://monzo.com", []string{"https://monzo.com/about"})
+
+	if err := c.SaveState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	if err := resumed.LoadState(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(resumed.frontier(), []string{"https://monzo.com/about"}) {
+		t.Fatalf("expected in-progress page in the frontier, actual: %v", resumed.frontier())
+	}
+
+	if len(resumed.result) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(resumed.result))
+	}
+
+	if resumed.referrerOf("https://monzo.com/about") != "https://monzo.com" {
+		t.Fatalf("expected referrer to be restored, actual: %v", resumed.referrers)
+	}
+
+	if resumed.firstWithHash("abc", "https://monzo.com/blog") != "https://monzo.com" {
+		t.Fatalf("expected hashes to be restored, actual: %v", resumed.hashes)
+	}
+
+	if resumed.graph.distances("https://monzo.com")["https://monzo.com/about"] != 1 {
+		t.Fatal("expected graph to be restored")
+	}
+}
+
+func TestCrawlResumeAppendsToSqliteOutput(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/about">about</a><a href="/blog">blog</a>`,
+		"/about": `about`,
+		"/blog":  `blog`,
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, "crawl.ckpt")
+	output := filepath.Join(dir, "crawl.db")
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Sqlite, OutputFile: output})
+	c.record(Result{URL: server.URL, Status: 200, Count: 2, Links: []string{server.URL + "/about", server.URL + "/blog"}})
+	c.cache.AddSlice([]string{server.URL, server.URL + "/about", server.URL + "/blog"})
+	c.visited.Add(server.URL)
+	if err := c.SaveState(checkpointFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Sqlite, OutputFile: output, ResumeFile: checkpointFile})
+	resumed.Crawl(server.URL)
+
+	db, err := sql.Open("sqlite", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var pages int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pages").Scan(&pages); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 3 {
+		t.Fatalf("expected pages: %d, actual pages: %d", 3, pages)
+	}
+}
@@ -1,19 +1,24 @@
 package crawler
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"log"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/denis101/monzo-techtest/parser"
 	"github.com/denis101/monzo-techtest/scheduler"
+	"github.com/denis101/monzo-techtest/set"
 	"github.com/hashicorp/go-hclog"
 	"github.com/pterm/pterm"
 
@@ -23,44 +28,233 @@ import (
 type CrawlerOutputFormat string
 
 const (
-	Output_Stdout CrawlerOutputFormat = "stdout"
-	Output_Json   CrawlerOutputFormat = "json"
-	Output_Xml    CrawlerOutputFormat = "xml"
+	Output_Stdout  CrawlerOutputFormat = "stdout"
+	Output_Json    CrawlerOutputFormat = "json"
+	Output_Xml     CrawlerOutputFormat = "xml"
+	Output_Sqlite  CrawlerOutputFormat = "sqlite"
+	Output_Parquet CrawlerOutputFormat = "parquet"
+	Output_Edges   CrawlerOutputFormat = "edges"
+	Output_Dot     CrawlerOutputFormat = "dot"
+	Output_Sitemap CrawlerOutputFormat = "sitemap"
+	Output_Csv     CrawlerOutputFormat = "csv"
+	Output_Jsonl   CrawlerOutputFormat = "jsonl"
 )
 
+var OutputFormats []CrawlerOutputFormat = []CrawlerOutputFormat{
+	Output_Stdout,
+	Output_Json,
+	Output_Xml,
+	Output_Sqlite,
+	Output_Parquet,
+	Output_Edges,
+	Output_Dot,
+	Output_Sitemap,
+	Output_Csv,
+	Output_Jsonl,
+}
+
+const DefaultEdgeDelimiter = "\t"
+
 const UpdateDuration = time.Millisecond * 200
 
+const DefaultCheckpointInterval = time.Second * 30
+
+const DefaultStatsInterval = time.Second * 5
+
+const DefaultTopN = 10
+
+var DefaultFailOn []string = []string{"4xx", "5xx"}
+
 var SpinnerSequence []string = []string{"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷"}
 
 type CrawlerOptions struct {
-	OutputFormat      CrawlerOutputFormat `structs:",omitempty"`
-	OutputFile        string              `structs:",omitempty"`
-	MaxWorkers        int
-	Interactive       bool
-	RequestDeadline   int
-	IgnoreFragments   bool
-	IgnoredExtensions []string `structs:",omitempty"`
-	IgnoredPaths      []string `structs:",omitempty"`
+	OutputFormat         CrawlerOutputFormat `structs:",omitempty"`
+	OutputFile           string              `structs:",omitempty"`
+	MaxWorkers           int
+	Interactive          bool
+	HandleSignals        bool
+	RequestDeadline      int
+	DialTimeout          time.Duration
+	TLSHandshakeTimeout  time.Duration
+	HeaderTimeout        time.Duration
+	MaxIdleConnsPerHost  int
+	MaxConnsPerHost      int
+	IdleConnTimeout      time.Duration
+	DisableKeepAlives    bool
+	IgnoreFragments      bool
+	IgnoredExtensions    []string `structs:",omitempty"`
+	IgnoredPaths         []string `structs:",omitempty"`
+	DenyPathPrefixes     []string `structs:",omitempty"`
+	DenyHosts            []string `structs:",omitempty"`
+	IncludeRegex         string   `structs:",omitempty"`
+	ExcludeRegex         string   `structs:",omitempty"`
+	CanonicalizeScheme   bool
+	AutoTune             bool
+	MinWorkers           int
+	TargetLatency        time.Duration
+	TuneWindow           int
+	RecordErrors         bool
+	Summary              bool
+	TopN                 int
+	HTTPCacheDir         string   `structs:",omitempty"`
+	SoftErrorMarkers     []string `structs:",omitempty"`
+	SkipSoftErrorLinks   bool
+	ParseInlineJSLinks   bool
+	FailFast             bool
+	FailOn               []StatusRange `structs:",omitempty"`
+	MaxInFlightRequests  int
+	DedupByContent       bool
+	CheckpointFile       string `structs:",omitempty"`
+	CheckpointInterval   time.Duration
+	ResumeFile           string `structs:",omitempty"`
+	EdgeDelimiter        string `structs:",omitempty"`
+	Accept               string `structs:",omitempty"`
+	UserAgent            string `structs:",omitempty"`
+	RespectNofollow      bool
+	RequestsPerSecond    float64
+	MaxRetries           int
+	RetryBackoff         time.Duration
+	MaxRedirects         int
+	NormalizeQuery       bool
+	AllowedDomains       []string `structs:",omitempty"`
+	ContentTypes         []string `structs:",omitempty"`
+	StripQueryParams     []string `structs:",omitempty"`
+	CookieFile           string   `structs:",omitempty"`
+	Radius               int
+	MaxDepth             int
+	BreadthFirst         bool
+	MaxPages             int
+	MaxDuration          time.Duration
+	SSEAddr              string            `structs:",omitempty"`
+	HostAliases          map[string]string `structs:",omitempty"`
+	FollowPagination     bool
+	FollowMetaRefresh    bool
+	UseCanonical         bool
+	SeedFromSitemap      bool
+	MaxOutput            int
+	ScopeGlobs           []string `structs:",omitempty"`
+	RecordReferrer       bool
+	RecordExternal       bool
+	CheckExternal        bool
+	MaxTotalBytes        int64
+	MaxBodyBytes         int64
+	ProbeWithHead        bool
+	HeadOnly             bool
+	CheckAssets          bool
+	RequireContentLength bool
+	MeasureUnknownBodies bool
+	ParseCSSUrls         bool
+	CheckInternalLinks   bool
+	ClassifyAuthRequired bool
+	PlateauThreshold     float64
+	PlateauWindow        int
+	StatsFile            string `structs:",omitempty"`
+	StatsInterval        time.Duration
+	Headers              map[string]string            `structs:"-"`
+	HeadersPerHost       map[string]map[string]string `structs:"-"`
+	OnResult             func(Result)                 `structs:"-"`
+	OutputWriter         io.Writer                    `structs:"-"`
+	Client               *http.Client                 `structs:"-"`
+	Cache                parser.Cache                 `structs:"-"`
+	Progress             chan<- ProgressEvent         `structs:"-"`
+	BasicAuthUser        string
+	BasicAuthPass        string   `structs:"-"`
+	BasicAuthHosts       []string `structs:",omitempty"`
+	InsecureSkipVerify   bool
+	ProxyURL             string `structs:"-"`
 }
 
 type Crawler struct {
-	scheduler *scheduler.Scheduler[string]
-	parser    *parser.Parser
-	cache     hashSet
-	visited   hashSet
-	opts      CrawlerOptions
-	result    []crawlerResult
-	quit      chan os.Signal
-	ticker    *time.Ticker
-	ui        crawlerUi
+	scheduler    *scheduler.Scheduler[crawlTask]
+	parser       *parser.Parser
+	cache        set.Set[string]
+	visited      set.Set[string]
+	claimed      set.Set[string]
+	assets       set.Set[string]
+	opts         CrawlerOptions
+	result       []Result
+	resultLock   sync.Mutex
+	quit         chan os.Signal
+	ticker       *time.Ticker
+	ui           crawlerUi
+	writer       resultWriter
+	stopping     atomic.Bool
+	aborted      atomic.Bool
+	failures     atomic.Int32
+	fetched      atomic.Int32
+	current      atomic.Value
+	written      atomic.Int64
+	byteCapped   atomic.Bool
+	hashes       map[string]string
+	hashLock     sync.Mutex
+	depths       map[int]int
+	recorded     map[string]int
+	inDegree     map[string]int
+	discovered   map[string]int
+	depthLock    sync.Mutex
+	seed         string
+	graph        linkGraph
+	deferred     map[string]crawlTask
+	graphLock    sync.Mutex
+	sse          *sseBroker
+	sseServer    *http.Server
+	referrers    map[string]string
+	referrerLock sync.Mutex
+	broken       []Result
+	inbound      map[string][]string
+	inboundLock  sync.Mutex
+	external     map[string]ExternalLink
+	externalLock sync.Mutex
+	started      time.Time
+	finished     time.Time
+	statuses     map[int]int
+	plateau      plateauWindow
+	timings      []pageTiming
+	ctx          context.Context
+	callbackLock sync.Mutex
+}
+
+type crawlTask struct {
+	url      string
+	depth    int
+	referrer string
 }
 
-type crawlerResult struct {
-	URL    string   `json:"url" xml:"url,attr"`
-	Status int      `json:"status" xml:"status,attr"`
-	Error  string   `json:"error,omitempty" xml:"error,attr"`
-	Count  int      `json:"count" xml:"linkCount,attr"`
-	Links  []string `json:"links,omitempty" xml:"link"`
+type CrawlOutcome struct {
+	Pages       int
+	Failures    int
+	BrokenLinks int
+	Aborted     bool
+}
+
+type Result struct {
+	XMLName       xml.Name      `json:"-" xml:"crawlerResult"`
+	URL           string        `json:"url" xml:"url,attr"`
+	Status        int           `json:"status" xml:"status,attr"`
+	Error         string        `json:"error,omitempty" xml:"error,attr"`
+	Depth         int           `json:"depth" xml:"depth,attr"`
+	SoftError     string        `json:"softError,omitempty" xml:"softError,attr,omitempty"`
+	DuplicateOf   string        `json:"duplicateOf,omitempty" xml:"duplicateOf,attr,omitempty"`
+	Canonical     string        `json:"canonical,omitempty" xml:"canonical,attr,omitempty"`
+	Title         string        `json:"title,omitempty" xml:"title,attr,omitempty"`
+	FirstSeenFrom string        `json:"firstSeenFrom,omitempty" xml:"firstSeenFrom,attr,omitempty"`
+	Referrer      string        `json:"referrer,omitempty" xml:"referrer,attr,omitempty"`
+	Count         int           `json:"count" xml:"linkCount,attr"`
+	Links         []string      `json:"links,omitempty" xml:"link"`
+	ExternalLinks []string      `json:"externalLinks,omitempty" xml:"externalLink,omitempty"`
+	Pagination    []string      `json:"pagination,omitempty" xml:"pagination,omitempty"`
+	Asset         bool          `json:"asset,omitempty" xml:"asset,attr,omitempty"`
+	CSS           bool          `json:"css,omitempty" xml:"css,attr,omitempty"`
+	AuthRequired  bool          `json:"authRequired,omitempty" xml:"authRequired,attr,omitempty"`
+	Size          int64         `json:"size,omitempty" xml:"size,attr,omitempty"`
+	ResponseTime  time.Duration `json:"responseTime,omitempty" xml:"responseTime,attr,omitempty"`
+}
+
+func (o CrawlOutcome) ExitCode() int {
+	if o.Aborted || o.Failures > 0 || o.BrokenLinks > 0 {
+		return 1
+	}
+	return 0
 }
 
 type crawlerUi struct {
@@ -69,40 +263,178 @@ type crawlerUi struct {
 	spinners []*pterm.SpinnerPrinter
 }
 
-func NewCrawler(opts CrawlerOptions) *Crawler {
+func newParser(opts CrawlerOptions) (*parser.Parser, error) {
+	maxIdleConnsPerHost := opts.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = opts.MaxWorkers
+	}
+
+	return parser.NewParser(parser.ParserOptions{
+		Timeout:               time.Second * time.Duration(opts.RequestDeadline),
+		SameSubdomain:         true,
+		Distinct:              true,
+		IgnoreFragments:       opts.IgnoreFragments,
+		IgnoredExtensions:     opts.IgnoredExtensions,
+		IgnoredPaths:          opts.IgnoredPaths,
+		DenyPathPrefixes:      opts.DenyPathPrefixes,
+		DenyHosts:             opts.DenyHosts,
+		IncludeRegex:          opts.IncludeRegex,
+		ExcludeRegex:          opts.ExcludeRegex,
+		CanonicalizeScheme:    opts.CanonicalizeScheme,
+		HTTPCacheDir:          opts.HTTPCacheDir,
+		SoftErrorMarkers:      opts.SoftErrorMarkers,
+		ParseInlineJSLinks:    opts.ParseInlineJSLinks,
+		MaxInFlightRequests:   opts.MaxInFlightRequests,
+		Accept:                opts.Accept,
+		CookieFile:            opts.CookieFile,
+		HostAliases:           opts.HostAliases,
+		FollowPagination:      opts.FollowPagination,
+		FollowMetaRefresh:     opts.FollowMetaRefresh,
+		RecordExternal:        opts.RecordExternal || opts.CheckExternal,
+		ScopeGlobs:            opts.ScopeGlobs,
+		HeadOnly:              opts.HeadOnly,
+		HashContent:           opts.DedupByContent,
+		CollectAssets:         opts.CheckAssets,
+		RequireContentLength:  opts.RequireContentLength,
+		MeasureUnknownBodies:  opts.MeasureUnknownBodies,
+		ParseCSSUrls:          opts.ParseCSSUrls,
+		UserAgent:             opts.UserAgent,
+		RespectNofollow:       opts.RespectNofollow,
+		RequestsPerSecond:     opts.RequestsPerSecond,
+		MaxRetries:            opts.MaxRetries,
+		RetryBackoff:          opts.RetryBackoff,
+		MaxRedirects:          opts.MaxRedirects,
+		NormalizeQuery:        opts.NormalizeQuery,
+		AllowedDomains:        opts.AllowedDomains,
+		StripQueryParams:      opts.StripQueryParams,
+		Headers:               opts.Headers,
+		HeadersPerHost:        opts.HeadersPerHost,
+		ParseableContentTypes: opts.ContentTypes,
+		MaxBodyBytes:          opts.MaxBodyBytes,
+		ProbeWithHead:         opts.ProbeWithHead,
+		BasicAuthUser:         opts.BasicAuthUser,
+		BasicAuthPass:         opts.BasicAuthPass,
+		BasicAuthHosts:        opts.BasicAuthHosts,
+		InsecureSkipVerify:    opts.InsecureSkipVerify,
+		ProxyURL:              opts.ProxyURL,
+		DialTimeout:           opts.DialTimeout,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.HeaderTimeout,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		Client:                opts.Client,
+		Cache:                 opts.Cache,
+	})
+}
+
+func Diagnose(opts CrawlerOptions, url string) (parser.Diagnostic, error) {
+	p, err := newParser(opts)
+	if err != nil {
+		return parser.Diagnostic{}, err
+	}
+
+	input, err := p.NormalizeUrl(url)
+	if err != nil {
+		return parser.Diagnostic{}, err
+	}
+
+	return p.Diagnose(input)
+}
+
+func NewCrawler(opts CrawlerOptions) (*Crawler, error) {
 	hclog.Default().Info("crawler initialised", "CrawlerOptions", structs.Map(opts))
+	p, err := newParser(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Crawler{
-		scheduler: scheduler.NewScheduler[string](scheduler.SchedulerOptions{
-			MaxWorkers:  opts.MaxWorkers,
-			Interactive: opts.Interactive,
-		}),
-		parser: parser.NewParser(parser.ParserOptions{
-			Timeout:           time.Second * time.Duration(opts.RequestDeadline),
-			SameSubdomain:     true,
-			Distinct:          true,
-			IgnoreFragments:   opts.IgnoreFragments,
-			IgnoredExtensions: opts.IgnoredExtensions,
-			IgnoredPaths:      opts.IgnoredPaths,
+		scheduler: scheduler.NewScheduler[crawlTask](scheduler.SchedulerOptions{
+			MaxWorkers:    opts.MaxWorkers,
+			Interactive:   opts.Interactive,
+			AutoTune:      opts.AutoTune,
+			MinWorkers:    opts.MinWorkers,
+			TargetLatency: opts.TargetLatency,
+			TuneWindow:    opts.TuneWindow,
+			Dedup:         true,
 		}),
-		opts: opts,
-		quit: make(chan os.Signal, 1),
+		parser:     p,
+		opts:       opts,
+		ctx:        context.Background(),
+		quit:       make(chan os.Signal, 1),
+		hashes:     make(map[string]string),
+		depths:     make(map[int]int),
+		recorded:   make(map[string]int),
+		inDegree:   make(map[string]int),
+		statuses:   make(map[int]int),
+		discovered: make(map[string]int),
+		referrers:  make(map[string]string),
+		inbound:    make(map[string][]string),
+		external:   make(map[string]ExternalLink),
+		deferred:   make(map[string]crawlTask),
+	}
+
+	resuming := false
+	if len(opts.ResumeFile) > 0 {
+		_, err := os.Stat(opts.ResumeFile)
+		resuming = err == nil
+	}
+
+	if opts.OutputFormat == Output_Sqlite {
+		writer, err := newSqliteWriter(c.outputFilename(), resuming)
+		if err != nil {
+			return nil, err
+		}
+		c.writer = writer
+	} else if opts.OutputFormat == Output_Parquet {
+		writer, err := newParquetWriter(c.outputFilename(), resuming)
+		if err != nil {
+			return nil, err
+		}
+		c.writer = writer
+	} else if opts.OutputFormat == Output_Jsonl {
+		var filename string
+		if len(opts.OutputFile) > 0 {
+			filename = c.outputFilename()
+		}
+
+		writer, err := newJsonlWriter(filename, resuming, c.output())
+		if err != nil {
+			return nil, err
+		}
+		c.writer = writer
+	}
+
+	if len(opts.SSEAddr) > 0 {
+		c.sse = newSseBroker()
 	}
 
 	if opts.Interactive {
-		c.ui = newUi(opts)
+		ui, err := newUi(opts)
+		if err != nil {
+			return nil, err
+		}
+		c.ui = ui
 		c.ui.multi.Start()
 	}
 
-	signal.Notify(c.quit, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	if opts.HandleSignals {
+		signal.Notify(c.quit, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	}
 	c.scheduler.WithHandler(c.handler)
-	return c
+	if opts.BreadthFirst {
+		c.scheduler.WithPriority(func(t crawlTask) int { return -t.depth })
+	}
+	return c, nil
 }
 
-func newUi(opts CrawlerOptions) crawlerUi {
+func newUi(opts CrawlerOptions) (crawlerUi, error) {
 	multi := pterm.DefaultMultiPrinter.WithUpdateDelay(UpdateDuration)
 	progress, err := pterm.DefaultProgressbar.WithWriter(multi.NewWriter()).Start()
 	if err != nil {
-		panic(err)
+		return crawlerUi{}, err
 	}
 
 	ui := crawlerUi{
@@ -110,65 +442,179 @@ func newUi(opts CrawlerOptions) crawlerUi {
 		progress: progress,
 	}
 
-	for i := 0; i < opts.MaxWorkers; i++ {
+	if _, err := ui.spinner(opts.MaxWorkers - 1); err != nil {
+		return crawlerUi{}, err
+	}
+
+	return ui, nil
+}
+
+func (ui *crawlerUi) spinner(id int) (*pterm.SpinnerPrinter, error) {
+	for len(ui.spinners) <= id {
 		spinner, err := pterm.DefaultSpinner.
 			WithSequence(SpinnerSequence...).
 			WithDelay(UpdateDuration).
-			WithWriter(multi.NewWriter()).
+			WithWriter(ui.multi.NewWriter()).
 			WithShowTimer(false).
 			Start()
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 
 		ui.spinners = append(ui.spinners, spinner)
 	}
 
-	return ui
+	return ui.spinners[id], nil
 }
 
-func (c *Crawler) Crawl(url string) {
-	c.ticker = time.NewTicker(UpdateDuration)
-	c.scheduler.Start()
+func (c *Crawler) Crawl(url string) (CrawlOutcome, error) {
+	return c.CrawlContext(context.Background(), url)
+}
 
-	input, err := parser.SanitiseUrl(url)
+func (c *Crawler) CrawlContext(ctx context.Context, url string) (CrawlOutcome, error) {
+	input, err := c.parser.NormalizeUrl(url)
 	if err != nil {
-		log.Fatal(err)
+		c.abandon()
+		return CrawlOutcome{}, err
 	}
+	input = c.parser.CanonicalHost(input)
+
+	if len(c.opts.ResumeFile) > 0 {
+		if err := c.LoadState(c.opts.ResumeFile); err != nil {
+			c.abandon()
+			return CrawlOutcome{}, err
+		}
+	}
+
+	c.ctx = ctx
+	c.started = time.Now()
+	c.ticker = time.NewTicker(UpdateDuration)
+	c.scheduler.Start()
 
 	hclog.Default().Debug("crawler ready, starting", "input", input)
 
-	c.cache.add(input)
-	c.scheduler.Dispatch([]string{input})
-	c.run()
+	if c.sse != nil {
+		c.startSse()
+	}
+
+	c.seed = input
+	c.graph.setRoot(input)
+	c.cache.Add(input)
+	tasks := append([]crawlTask{{url: input}}, c.frontierTasks()...)
+	if c.opts.SeedFromSitemap {
+		tasks = append(tasks, c.sitemapTasks(ctx, input)...)
+	}
+	c.scheduler.Dispatch(tasks)
+	err = c.run(ctx)
+
+	return CrawlOutcome{
+		Pages:       c.visited.Size(),
+		Failures:    int(c.failures.Load()),
+		BrokenLinks: c.brokenLinkCount(),
+		Aborted:     c.aborted.Load(),
+	}, err
+}
+
+func (c *Crawler) abandon() {
+	if c.opts.Interactive {
+		c.ui.multi.Stop()
+	}
+	signal.Stop(c.quit)
+}
+
+func (c *Crawler) stop() {
+	c.stopping.Store(true)
+	select {
+	case c.quit <- syscall.SIGQUIT:
+	default:
+	}
 }
 
-func (c *Crawler) run() {
+func (c *Crawler) run(ctx context.Context) (err error) {
 	defer func(c *Crawler) {
 		if c.opts.Interactive {
 			c.ui.multi.Stop()
 		}
 
 		c.scheduler.Stop()
+		c.parser.CloseIdleConnections()
+		c.finished = time.Now()
+		c.settleDepths()
 		c.ticker.Stop()
-		c.done()
+		c.progress(c.visited.Size(), c.cache.Size())
+		if len(c.opts.CheckpointFile) > 0 {
+			c.checkpoint()
+		}
+		if len(c.opts.StatsFile) > 0 {
+			c.writeStats(true)
+		}
+		if c.opts.Summary {
+			c.summary()
+		}
+		err = c.done()
+		if reportErr := c.writeReports(); err == nil {
+			err = reportErr
+		}
+		if c.sse != nil {
+			c.stopSse()
+		}
 	}(c)
 
+	checkpointInterval := c.opts.CheckpointInterval
+	if checkpointInterval <= 0 {
+		checkpointInterval = DefaultCheckpointInterval
+	}
+	lastCheckpoint := time.Now()
+
+	statsInterval := c.opts.StatsInterval
+	if statsInterval <= 0 {
+		statsInterval = DefaultStatsInterval
+	}
+	lastStats := time.Now()
+
+	if c.opts.MaxDuration > 0 {
+		budget := time.AfterFunc(c.opts.MaxDuration, func() {
+			hclog.Default().Info("max duration reached, stopping crawl", "max", c.opts.MaxDuration)
+			c.stop()
+		})
+		defer budget.Stop()
+	}
+
 	for {
 		select {
 		case <-c.ticker.C:
-			visitedSize := c.visited.size()
-			cacheSize := c.cache.size()
+			if len(c.opts.CheckpointFile) > 0 && time.Since(lastCheckpoint) >= checkpointInterval {
+				c.checkpoint()
+				lastCheckpoint = time.Now()
+			}
+
+			if len(c.opts.StatsFile) > 0 && time.Since(lastStats) >= statsInterval {
+				c.writeStats(false)
+				lastStats = time.Now()
+			}
+
+			visitedSize := c.visited.Size()
+			cacheSize := c.cache.Size()
 			if c.opts.Interactive {
 				c.ui.progress.Current = visitedSize
 				c.ui.progress.Total = cacheSize
 			}
+			c.progress(visitedSize, cacheSize)
 
-			if visitedSize >= cacheSize {
-				c.quit <- syscall.SIGQUIT
+			if visitedSize >= cacheSize && c.scheduler.Idle() {
+				c.stop()
 			}
 		case rs := <-c.scheduler.WorkerState:
-			c.ui.spinners[rs[0].(int)].UpdateText(rs[1].(string))
+			spinner, err := c.ui.spinner(rs[0].(int))
+			if err != nil {
+				hclog.Default().Error("failed to start worker spinner", "worker", rs[0], "error", err)
+				continue
+			}
+			spinner.UpdateText(rs[1].(crawlTask).url)
+		case <-ctx.Done():
+			hclog.Default().Info("crawl cancelled, stopping", "error", ctx.Err())
+			c.stopping.Store(true)
+			return
 		case sig := <-c.quit:
 			if sig != syscall.SIGQUIT {
 				os.Exit(int(sig.(syscall.Signal)))
@@ -178,111 +624,578 @@ func (c *Crawler) run() {
 	}
 }
 
-func (c *Crawler) done() {
+func (c *Crawler) done() error {
 	hclog.Default().Debug("crawler finished.")
-	results := c.getResultString()
+
+	if c.writer != nil {
+		if err := c.writer.close(); err != nil {
+			return err
+		}
+		hclog.Default().Debug("wrote results to file", "filename", c.outputFilename())
+		return nil
+	}
+
+	if c.opts.CheckInternalLinks && len(c.opts.OutputFile) <= 0 {
+		return nil
+	}
+
+	results, err := c.getResultString()
+	if err != nil {
+		return err
+	}
 
 	if len(c.opts.OutputFile) <= 0 {
-		println(results)
-	} else {
-		outFile := c.opts.OutputFile
-		if c.opts.OutputFormat == Output_Json && !strings.HasSuffix(outFile, ".json") {
-			outFile += ".json"
-		} else if c.opts.OutputFormat == Output_Xml && !strings.HasSuffix(outFile, ".xml") {
-			outFile += ".xml"
+		_, err := fmt.Fprintln(c.output(), results)
+		return err
+	}
+
+	outFile := c.outputFilename()
+	if err := writeFile(outFile, results); err != nil {
+		return err
+	}
+	hclog.Default().Debug("wrote results to file", "filename", outFile)
+	return nil
+}
+
+func (c *Crawler) writeReports() error {
+	if c.opts.CheckInternalLinks {
+		if _, err := fmt.Fprint(c.output(), c.linkReport()); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.CheckExternal {
+		if _, err := fmt.Fprint(c.output(), c.externalReport()); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+func (c *Crawler) output() io.Writer {
+	if c.opts.OutputWriter != nil {
+		return c.opts.OutputWriter
+	}
+	return os.Stdout
+}
+
+func (c *Crawler) outputFilename() string {
+	outFile := c.opts.OutputFile
+	if c.opts.OutputFormat == Output_Json && !strings.HasSuffix(outFile, ".json") {
+		outFile += ".json"
+	} else if c.opts.OutputFormat == Output_Xml && !strings.HasSuffix(outFile, ".xml") {
+		outFile += ".xml"
+	} else if c.opts.OutputFormat == Output_Sqlite && !strings.HasSuffix(outFile, ".db") {
+		outFile += ".db"
+	} else if c.opts.OutputFormat == Output_Parquet && !strings.HasSuffix(outFile, ".parquet") {
+		outFile += ".parquet"
+	} else if c.opts.OutputFormat == Output_Dot && !strings.HasSuffix(outFile, ".dot") {
+		outFile += ".dot"
+	} else if c.opts.OutputFormat == Output_Sitemap && !strings.HasSuffix(outFile, ".xml") {
+		outFile += ".xml"
+	} else if c.opts.OutputFormat == Output_Csv && !strings.HasSuffix(outFile, ".csv") {
+		outFile += ".csv"
+	} else if c.opts.OutputFormat == Output_Jsonl && !strings.HasSuffix(outFile, ".jsonl") {
+		outFile += ".jsonl"
+	}
+	return outFile
+}
 
-		writeFile(outFile, results)
-		hclog.Default().Debug("wrote results to file", "filename", outFile)
+func (c *Crawler) reserveOutput() bool {
+	if c.opts.MaxOutput <= 0 {
+		return true
 	}
+
+	written := c.written.Add(1)
+	if written == int64(c.opts.MaxOutput)+1 {
+		hclog.Default().Debug("output limit reached, not writing further results", "max", c.opts.MaxOutput)
+	}
+	return written <= int64(c.opts.MaxOutput)
 }
 
-func (c *Crawler) getResultString() string {
+func (c *Crawler) outputResults() []Result {
+	if c.opts.MaxOutput <= 0 || len(c.result) <= c.opts.MaxOutput {
+		return c.result
+	}
+
+	hclog.Default().Debug("truncating output", "results", len(c.result), "max", c.opts.MaxOutput)
+	return c.result[:c.opts.MaxOutput]
+}
+
+func (c *Crawler) getResultString() (string, error) {
+	results := c.outputResults()
 	if c.opts.OutputFormat == Output_Json {
-		b, err := json.MarshalIndent(c.result, "", "  ")
-		if err != nil {
-			panic(err)
-		}
-		return string(b)
+		b, err := json.MarshalIndent(results, "", "  ")
+		return string(b), err
 	} else if c.opts.OutputFormat == Output_Xml {
-		b, err := xml.MarshalIndent(c.result, "", "  ")
-		if err != nil {
-			panic(err)
+		b, err := xml.MarshalIndent(results, "", "  ")
+		return string(b), err
+	} else if c.opts.OutputFormat == Output_Edges {
+		delimiter := c.opts.EdgeDelimiter
+		if len(delimiter) <= 0 {
+			delimiter = DefaultEdgeDelimiter
 		}
-		return string(b)
+
+		var builder strings.Builder
+		edges := make(map[string]bool)
+		for _, e := range results {
+			for _, l := range e.Links {
+				edge := e.URL + delimiter + l
+				if edges[edge] {
+					continue
+				}
+
+				edges[edge] = true
+				fmt.Fprintf(&builder, "%s\n", edge)
+			}
+		}
+		return builder.String(), nil
+	} else if c.opts.OutputFormat == Output_Csv {
+		return csvResults(results)
+	} else if c.opts.OutputFormat == Output_Sitemap {
+		return c.sitemap(results)
+	} else if c.opts.OutputFormat == Output_Dot {
+		var builder strings.Builder
+		builder.WriteString("digraph crawl {\n")
+		edges := make(map[string]bool)
+		for _, e := range results {
+			fmt.Fprintf(&builder, "  %s;\n", dotQuote(e.URL))
+			for _, l := range e.Links {
+				edge := dotQuote(e.URL) + " -> " + dotQuote(l)
+				if edges[edge] {
+					continue
+				}
+
+				edges[edge] = true
+				fmt.Fprintf(&builder, "  %s;\n", edge)
+			}
+		}
+		builder.WriteString("}\n")
+		return builder.String(), nil
 	} else {
 		var builder strings.Builder
-		for _, e := range c.result {
+		for _, e := range results {
 			fmt.Fprintf(&builder, "%s\n", e.URL)
 			for _, l := range e.Links {
 				fmt.Fprintf(&builder, "\t%s\n", l)
 			}
 		}
-		return builder.String()
+		return builder.String(), nil
 	}
 }
 
-func writeFile(filename string, data string) {
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+func writeFile(filename string, data string) error {
 	f, err := os.Create(filename)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer f.Close()
 
-	f.WriteString(data)
-	err = f.Sync()
-	if err != nil {
-		panic(err)
+	if _, err := f.WriteString(data); err != nil {
+		return err
 	}
+	return f.Sync()
 }
 
-func (c *Crawler) handler(input string) {
-	if c.visited.has(input) {
+func (c *Crawler) onResult(result Result) {
+	if c.opts.OnResult == nil {
 		return
 	}
 
-	output, err := c.parser.ParseLinks(input)
-	c.visited.add(input)
+	c.callbackLock.Lock()
+	defer c.callbackLock.Unlock()
+	result.Links = slices.Clone(result.Links)
+	result.ExternalLinks = slices.Clone(result.ExternalLinks)
+	result.Pagination = slices.Clone(result.Pagination)
+	c.opts.OnResult(result)
+}
+
+func (c *Crawler) record(result Result) {
+	if c.opts.ClassifyAuthRequired && isAuthRequired(result.Status) {
+		result.AuthRequired = true
+	}
+
+	if len(result.Error) <= 0 && !result.Asset {
+		c.onResult(result)
+	}
+
+	output := len(result.Error) <= 0 || c.opts.RecordErrors
+	if output && c.writer != nil && c.reserveOutput() {
+		if err := c.writer.write(result); err != nil {
+			hclog.Default().Error("failed to write result", "input", result.URL, "error", err)
+		}
+	}
+
+	c.resultLock.Lock()
+	if output && c.writer == nil {
+		c.result = append(c.result, result)
+	}
+	if !result.Asset {
+		c.depths[result.Depth]++
+		c.recorded[result.URL] = result.Depth
+		c.countInbound(result)
+	}
+	c.statuses[result.Status]++
+	if isBroken(result) {
+		c.broken = append(c.broken, result)
+	}
+	c.resultLock.Unlock()
+
+	if output && c.sse != nil {
+		c.sse.publish(result)
+	}
+
+	if !c.isFailure(result) {
+		return
+	}
+
+	c.failures.Add(1)
+	if c.opts.FailFast && !c.aborted.Swap(true) {
+		hclog.Default().Error("fail-fast, stopping crawl",
+			"input", result.URL,
+			"status", result.Status,
+			"error", result.Error,
+		)
+		c.stop()
+	}
+}
+
+func (c *Crawler) checkAssets(input string, depth int, assets []string, css bool) {
+	for _, asset := range assets {
+		if c.stopping.Load() {
+			return
+		}
+
+		if !c.assets.TryAdd(asset) {
+			continue
+		}
+
+		check, err := c.parser.CheckStatus(asset)
+		c.countBytes()
+		result := Result{
+			URL:           asset,
+			Status:        check.StatusCode,
+			Depth:         depth,
+			FirstSeenFrom: input,
+			Asset:         true,
+			CSS:           css,
+			Size:          check.ContentLength,
+		}
+
+		if err != nil {
+			if !c.opts.Interactive {
+				hclog.Default().Error("asset check failed", "input", asset, "page", input, "error", err)
+			}
+			result.Error = err.Error()
+		}
+
+		c.record(result)
+	}
+}
+
+func (c *Crawler) isFailure(result Result) bool {
+	if result.AuthRequired {
+		return false
+	}
+	return len(result.Error) > 0 || matchesStatus(c.opts.FailOn, result.Status)
+}
+
+func (c *Crawler) countBytes() {
+	total := c.parser.DownloadedBytes()
+	if c.opts.MaxTotalBytes <= 0 || total <= c.opts.MaxTotalBytes {
+		return
+	}
+
+	if !c.byteCapped.Swap(true) {
+		hclog.Default().Warn("byte cap reached, stopping crawl",
+			"downloaded", total,
+			"max", c.opts.MaxTotalBytes,
+		)
+		c.stop()
+	}
+}
+
+func (c *Crawler) addReferrers(referrer string, links []string) {
+	c.referrerLock.Lock()
+	defer c.referrerLock.Unlock()
+
+	for _, l := range links {
+		if _, ok := c.referrers[l]; ok || l == c.seed {
+			continue
+		}
+		c.referrers[l] = referrer
+	}
+}
+
+func (c *Crawler) referrerOf(input string) string {
+	c.referrerLock.Lock()
+	defer c.referrerLock.Unlock()
+
+	return c.referrers[input]
+}
+
+func (c *Crawler) firstSeenFrom(input string) string {
+	if !c.opts.RecordReferrer {
+		return ""
+	}
+	return c.referrerOf(input)
+}
+
+func (c *Crawler) firstWithHash(hash string, input string) string {
+	if len(hash) <= 0 {
+		return ""
+	}
+
+	c.hashLock.Lock()
+	defer c.hashLock.Unlock()
+
+	first, ok := c.hashes[hash]
+	if !ok {
+		c.hashes[hash] = input
+		return ""
+	}
+	return first
+}
+
+func (c *Crawler) claimCanonical(canonical string) bool {
+	if !c.claimed.TryAdd(canonical) {
+		return false
+	}
+
+	c.cache.Add(canonical)
+	c.visited.Add(canonical)
+	return true
+}
+
+func (c *Crawler) handler(task crawlTask) error {
+	input := task.url
+	if !c.claimed.TryAdd(input) {
+		return nil
+	}
+	if c.opts.MaxPages > 0 && int(c.fetched.Add(1)) > c.opts.MaxPages {
+		return nil
+	}
+	defer c.visited.Add(input)
+	task.depth = c.minDepth(task)
+	c.current.Store(input)
+
+	start := time.Now()
+	output, err := c.parser.ParseLinksContext(c.ctx, input)
+	c.recordTiming(input, time.Since(start), output.BodySize)
+	c.countBytes()
+	c.scheduler.Report(output.Latency, err != nil || output.StatusCode >= 500 || output.StatusCode == http.StatusTooManyRequests)
 
 	if err != nil {
 		if !c.opts.Interactive {
 			hclog.Default().Error(
-				fmt.Sprintf("[%d/%d]", c.visited.size(), c.cache.size()),
+				fmt.Sprintf("[%d/%d]", c.visited.Size(), c.cache.Size()),
 				"status", output.Status,
 				"input", input,
 				"error", err,
 			)
 		}
 
-		return
+		c.record(Result{
+			URL:           input,
+			Status:        output.StatusCode,
+			Error:         err.Error(),
+			Depth:         task.depth,
+			FirstSeenFrom: c.firstSeenFrom(input),
+			Referrer:      task.referrer,
+		})
+		return err
 	}
 
-	c.result = append(c.result, crawlerResult{
-		URL:    input,
-		Links:  output.Links,
-		Count:  len(output.Links),
-		Status: output.StatusCode,
+	var duplicateOf string
+	if c.opts.DedupByContent {
+		duplicateOf = c.firstWithHash(output.ContentHash, input)
+	}
+
+	source := input
+	var canonical string
+	if c.opts.UseCanonical && len(output.Canonical) > 0 && output.Canonical != input {
+		canonical = output.Canonical
+		if c.claimCanonical(canonical) {
+			source = canonical
+		} else if len(duplicateOf) <= 0 {
+			duplicateOf = canonical
+		}
+	}
+
+	c.record(Result{
+		URL:           input,
+		Links:         output.Links,
+		ExternalLinks: output.ExternalLinks,
+		Count:         len(output.Links),
+		Status:        output.StatusCode,
+		SoftError:     output.SoftError,
+		DuplicateOf:   duplicateOf,
+		Canonical:     canonical,
+		Title:         output.Title,
+		ResponseTime:  output.Latency,
+		Depth:         task.depth,
+		Pagination:    output.Pagination,
+		FirstSeenFrom: c.firstSeenFrom(input),
+		Referrer:      task.referrer,
 	})
 
-	visited := c.visited.slice()
+	if c.opts.MaxPages > 0 && int(c.fetched.Load()) >= c.opts.MaxPages && !c.stopping.Load() {
+		hclog.Default().Info("max pages reached, stopping crawl", "max", c.opts.MaxPages)
+		c.stop()
+	}
+
+	if c.opts.CheckInternalLinks {
+		c.addInbound(source, output.Links)
+		c.addInbound(source, output.Assets)
+		c.addInbound(source, output.CSSAssets)
+	}
+
+	if c.opts.CheckAssets && !c.stopping.Load() {
+		c.checkAssets(source, task.depth+1, output.Assets, false)
+		c.checkAssets(source, task.depth+1, output.CSSAssets, true)
+	}
+
+	if c.opts.CheckExternal && !c.stopping.Load() {
+		c.checkExternal(source, output.ExternalLinks)
+	}
+
+	if len(duplicateOf) > 0 {
+		if !c.opts.Interactive {
+			hclog.Default().Debug("duplicate content, not following links",
+				"input", input,
+				"duplicateOf", duplicateOf,
+			)
+		}
+
+		return nil
+	}
+
+	if len(output.SoftError) > 0 && c.opts.SkipSoftErrorLinks {
+		if !c.opts.Interactive {
+			hclog.Default().Debug("soft error, not following links",
+				"input", input,
+				"marker", output.SoftError,
+			)
+		}
+
+		return nil
+	}
+
+	if c.opts.MaxDepth > 0 && task.depth >= c.opts.MaxDepth {
+		if !c.opts.Interactive {
+			hclog.Default().Debug("max depth reached, not following links",
+				"input", input,
+				"depth", task.depth,
+			)
+		}
+
+		return nil
+	}
+
+	tasks := newCrawlTasks(output.Links, task.depth+1, source)
+	for i, t := range tasks {
+		tasks[i].depth = c.minDepth(t)
+	}
+
+	if c.opts.Radius > 0 {
+		tasks = c.withinRadius(input, tasks)
+	}
+
+	if c.opts.PlateauThreshold > 0 {
+		c.observePlateau(output.Links)
+	}
+
+	visited := c.visited.Slice()
 	nonVisitedLinks := []string{}
-	for _, link := range output.Links {
-		if slices.Contains(visited, link) {
+	for _, t := range tasks {
+		if slices.Contains(visited, t.url) {
 			continue
 		}
 
-		nonVisitedLinks = append(nonVisitedLinks, link)
+		nonVisitedLinks = append(nonVisitedLinks, t.url)
 	}
 
-	c.cache.addSlice(nonVisitedLinks)
+	c.addReferrers(source, nonVisitedLinks)
+
+	c.cache.AddSlice(nonVisitedLinks)
 	if !c.opts.Interactive {
 		hclog.Default().Debug("task complete",
 			"status", output.StatusCode,
 			"input", input,
-			"visited", c.visited.size(),
-			"total", c.cache.size(),
+			"visited", c.visited.Size(),
+			"total", c.cache.Size(),
 			"new", len(nonVisitedLinks),
 		)
 	}
 
-	c.scheduler.Dispatch(output.Links)
+	if c.stopping.Load() {
+		return nil
+	}
+
+	c.scheduler.Dispatch(tasks)
+	return nil
+}
+
+func (c *Crawler) withinRadius(source string, tasks []crawlTask) []crawlTask {
+	links := make([]string, len(tasks))
+	for i, t := range tasks {
+		links[i] = t.url
+	}
+	changed := c.graph.addEdges(source, links)
+
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+
+	for _, t := range tasks {
+		if c.visited.Has(t.url) {
+			continue
+		}
+
+		if d, ok := c.deferred[t.url]; !ok || t.depth < d.depth {
+			c.deferred[t.url] = t
+		}
+	}
+
+	candidates := links
+	for url := range changed {
+		candidates = append(candidates, url)
+	}
+
+	var within []crawlTask
+	for _, url := range candidates {
+		t, ok := c.deferred[url]
+		if !ok {
+			continue
+		}
+
+		if d, ok := c.graph.distanceTo(url); ok && d <= c.opts.Radius {
+			within = append(within, t)
+			delete(c.deferred, url)
+		}
+	}
+	return within
+}
+
+func (c *Crawler) minDepth(task crawlTask) int {
+	c.depthLock.Lock()
+	defer c.depthLock.Unlock()
+
+	if d, ok := c.discovered[task.url]; ok && d <= task.depth {
+		return d
+	}
+
+	c.discovered[task.url] = task.depth
+	return task.depth
+}
+
+func newCrawlTasks(links []string, depth int, referrer string) []crawlTask {
+	tasks := make([]crawlTask, len(links))
+	for i, l := range links {
+		tasks[i] = crawlTask{url: l, depth: depth, referrer: referrer}
+	}
+	return tasks
 }
@@ -0,0 +1,1088 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCrawlFailFastAborts(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/broken">broken</a><a href="/about">about</a>`,
+		"/about": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	outcome, err := getTestCrawler(CrawlerOptions{MaxWorkers: 1, FailFast: true, FailOn: getTestFailOn()}).Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !outcome.Aborted {
+		t.Fatal("expected crawl to abort")
+	}
+
+	if outcome.ExitCode() == 0 {
+		t.Fatal("expected non-zero exit code")
+	}
+}
+
+func TestCrawlWithoutFailFastCompletes(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/broken">broken</a><a href="/about">about</a>`,
+		"/about": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome.Aborted {
+		t.Fatal("expected crawl not to abort")
+	}
+
+	if len(c.result) != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, len(c.result))
+	}
+
+	if outcome.Failures != 0 || outcome.ExitCode() != 0 {
+		t.Fatalf("expected no failures without fail-on, actual: %d", outcome.Failures)
+	}
+}
+
+func TestCrawlFailOnSetsExitCode(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/broken">broken</a><a href="/about">about</a>`,
+		"/about": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	outcome, err := getTestCrawler(CrawlerOptions{MaxWorkers: 1, FailOn: getTestFailOn()}).Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome.Aborted {
+		t.Fatal("expected crawl not to abort")
+	}
+
+	if outcome.Pages != 3 {
+		t.Fatalf("expected pages: %d, actual pages: %d", 3, outcome.Pages)
+	}
+
+	if outcome.Failures != 1 {
+		t.Fatalf("expected failures: %d, actual failures: %d", 1, outcome.Failures)
+	}
+
+	if outcome.ExitCode() != 1 {
+		t.Fatalf("expected exit code: %d, actual exit code: %d", 1, outcome.ExitCode())
+	}
+}
+
+func TestCrawlDedupByContent(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/page">page</a><a href="/print">print</a>`,
+		"/page":  `<a href="/child">child</a>`,
+		"/print": `<a href="/child">child</a>`,
+		"/child": `child`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, DedupByContent: true})
+	c.Crawl(server.URL)
+
+	duplicates := 0
+	for _, r := range c.result {
+		if len(r.DuplicateOf) <= 0 {
+			continue
+		}
+
+		duplicates++
+		if r.URL != server.URL+"/page" && r.URL != server.URL+"/print" {
+			t.Fatalf("unexpected duplicate %s", r.URL)
+		}
+	}
+
+	if duplicates != 1 {
+		t.Fatalf("expected duplicates: %d, actual duplicates: %d", 1, duplicates)
+	}
+}
+
+func TestCrawlDedupByContentSkipsUnhashed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a>`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, DedupByContent: true})
+	c.Crawl(server.URL)
+
+	if len(c.result) != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, len(c.result))
+	}
+
+	for _, r := range c.result {
+		if len(r.DuplicateOf) > 0 {
+			t.Fatalf("unexpected duplicate %s of %s", r.URL, r.DuplicateOf)
+		}
+	}
+}
+
+func TestCrawlUseCanonical(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/page">page</a>`,
+		"/page":  `<link rel="canonical" href="/page"><a href="/child">child</a><a href="/print">print</a>`,
+		"/print": `<link rel="canonical" href="/page"><a href="/print-only">print only</a>`,
+		"/child": `child`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, UseCanonical: true})
+	c.Crawl(server.URL)
+
+	results := make(map[string]Result)
+	for _, r := range c.result {
+		results[r.URL] = r
+	}
+
+	printView := results[server.URL+"/print"]
+	if printView.DuplicateOf != server.URL+"/page" || printView.Canonical != server.URL+"/page" {
+		t.Fatalf("expected: %s, actual duplicate: %s, actual canonical: %s", server.URL+"/page", printView.DuplicateOf, printView.Canonical)
+	}
+
+	if _, ok := results[server.URL+"/print-only"]; ok {
+		t.Fatalf("expected links of the duplicate not to be followed")
+	}
+
+	if len(results[server.URL+"/page"].Canonical) > 0 {
+		t.Fatalf("expected no canonical for a self-referencing page, actual: %s", results[server.URL+"/page"].Canonical)
+	}
+}
+
+func TestCrawlUseCanonicalAttributesLinks(t *testing.T) {
+	var pageHits atomic.Int32
+	site := newTestSite(map[string]string{
+		"/":      `<a href="/print">print</a>`,
+		"/print": `<link rel="canonical" href="/page"><a href="/child">child</a><a href="/page">page</a>`,
+		"/child": `child`,
+	})
+	defer site.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			pageHits.Add(1)
+		}
+		site.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, UseCanonical: true, RecordReferrer: true})
+	c.Crawl(server.URL)
+
+	if pageHits.Load() != 0 {
+		t.Fatalf("expected canonical hits: %d, actual: %d", 0, pageHits.Load())
+	}
+
+	for _, r := range c.result {
+		if r.URL == server.URL+"/print" && len(r.DuplicateOf) > 0 {
+			t.Fatalf("unexpected duplicate %s of %s", r.URL, r.DuplicateOf)
+		}
+
+		if r.URL == server.URL+"/child" && r.FirstSeenFrom != server.URL+"/page" {
+			t.Fatalf("expected: %s, actual: %s", server.URL+"/page", r.FirstSeenFrom)
+		}
+	}
+
+	if len(c.result) != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, len(c.result))
+	}
+}
+
+func TestGetResultStringEdges(t *testing.T) {
+	c := &Crawler{
+		opts: CrawlerOptions{OutputFormat: Output_Edges},
+		result: []Result{
+			{URL: "https://monzo.com", Links: []string{"https://monzo.com/about", "https://monzo.com/blog", "https://monzo.com/about"}},
+			{URL: "https://monzo.com/about", Links: []string{"https://monzo.com"}},
+		},
+	}
+
+	expected := "https://monzo.com\thttps://monzo.com/about\n" +
+		"https://monzo.com\thttps://monzo.com/blog\n" +
+		"https://monzo.com/about\thttps://monzo.com\n"
+	if resultString(t, c) != expected {
+		t.Fatalf("expected: %q, actual: %q", expected, resultString(t, c))
+	}
+
+	c.opts.EdgeDelimiter = ","
+	if !strings.HasPrefix(resultString(t, c), "https://monzo.com,https://monzo.com/about\n") {
+		t.Fatalf("expected comma delimited edges, actual: %q", resultString(t, c))
+	}
+}
+
+func TestGetResultStringDot(t *testing.T) {
+	c := &Crawler{
+		opts: CrawlerOptions{OutputFormat: Output_Dot},
+		result: []Result{
+			{URL: "https://monzo.com", Links: []string{"https://monzo.com/about", "https://monzo.com/about"}},
+			{URL: "https://monzo.com/about", Links: []string{"https://monzo.com", `https://monzo.com/"quoted"`}},
+		},
+	}
+
+	dot := resultString(t, c)
+	lines := strings.Split(strings.TrimSpace(dot), "\n")
+	if lines[0] != "digraph crawl {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("expected a digraph, actual: %q", dot)
+	}
+
+	expected := []string{
+		`  "https://monzo.com";`,
+		`  "https://monzo.com" -> "https://monzo.com/about";`,
+		`  "https://monzo.com/about";`,
+		`  "https://monzo.com/about" -> "https://monzo.com";`,
+		`  "https://monzo.com/about" -> "https://monzo.com/\"quoted\"";`,
+	}
+	if !slices.Equal(lines[1:len(lines)-1], expected) {
+		t.Fatalf("expected: %q, actual: %q", expected, lines[1:len(lines)-1])
+	}
+
+	c.opts.OutputFile = "site"
+	if c.outputFilename() != "site.dot" {
+		t.Fatalf("expected: %s, actual: %s", "site.dot", c.outputFilename())
+	}
+}
+
+func TestGetResultStringMaxOutput(t *testing.T) {
+	c := &Crawler{
+		opts: CrawlerOptions{OutputFormat: Output_Stdout, MaxOutput: 2},
+		result: []Result{
+			{URL: "https://monzo.com"},
+			{URL: "https://monzo.com/about"},
+			{URL: "https://monzo.com/blog"},
+		},
+	}
+
+	expected := "https://monzo.com\nhttps://monzo.com/about\n"
+	if resultString(t, c) != expected {
+		t.Fatalf("expected: %q, actual: %q", expected, resultString(t, c))
+	}
+
+	if len(c.result) != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, len(c.result))
+	}
+
+	c.opts.MaxOutput = 0
+	if strings.Count(resultString(t, c), "\n") != 3 {
+		t.Fatalf("expected all results, actual: %q", resultString(t, c))
+	}
+}
+
+func TestCrawlAliasedSeedCrawledOnce(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		fmt.Fprint(w, `<a href="/">home</a>`)
+	}))
+	defer server.Close()
+
+	aliased := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, HostAliases: map[string]string{"localhost": "127.0.0.1"}})
+	c.Crawl(aliased)
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected hits: %d, actual hits: %d", 1, hits.Load())
+	}
+
+	if len(c.result) != 1 || c.result[0].URL != server.URL {
+		t.Fatalf("expected only %s to be crawled, actual: %v", server.URL, c.result)
+	}
+}
+
+func TestCrawlRecordErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/broken" {
+			w.Header().Set("Content-Length", "100")
+			fmt.Fprint(w, "<a")
+			return
+		}
+		fmt.Fprint(w, `<a href="/broken">broken</a>`)
+	}))
+	defer server.Close()
+
+	for _, recordErrors := range []bool{false, true} {
+		c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, RecordErrors: recordErrors})
+		outcome, err := c.Crawl(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if outcome.Failures != 1 {
+			t.Fatalf("expected failures: %d, actual failures: %d", 1, outcome.Failures)
+		}
+
+		expected := 1
+		if recordErrors {
+			expected = 2
+		}
+
+		if len(c.result) != expected {
+			t.Fatalf("expected len: %d, actual len: %d", expected, len(c.result))
+		}
+
+		if recordErrors && len(c.result[1].Error) <= 0 {
+			t.Fatalf("expected an error for %s", c.result[1].URL)
+		}
+	}
+}
+
+func TestCrawlRecordsReferrer(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `<a href="/b">b</a><a href="/">home</a>`,
+		"/b": `<a href="/a">a</a><a href="/missing">missing</a>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, RecordReferrer: true})
+	c.Crawl(server.URL)
+
+	referrers := make(map[string]string)
+	for _, r := range c.result {
+		referrers[strings.TrimPrefix(r.URL, server.URL)] = strings.TrimPrefix(r.FirstSeenFrom, server.URL)
+	}
+
+	expected := map[string]string{"": "", "/a": "", "/b": "/a", "/missing": "/b"}
+	for path, referrer := range expected {
+		actual, ok := referrers[path]
+		if !ok {
+			t.Fatalf("expected %s to be crawled, actual: %v", path, referrers)
+		}
+
+		if actual != referrer {
+			t.Fatalf("expected: %s, actual: %s", referrer, actual)
+		}
+	}
+}
+
+func TestCrawlResultReferrer(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":       `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a":      `<a href="/a/deep">deep</a><a href="/">home</a>`,
+		"/b":      `<a href="/b/deep">deep</a><a href="/">home</a>`,
+		"/a/deep": `<a href="/missing">missing</a><a href="/a">a</a>`,
+		"/b/deep": `<a href="/b">b</a>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2})
+	c.Crawl(server.URL)
+
+	referrers := make(map[string]string)
+	for _, r := range c.Results() {
+		if len(r.FirstSeenFrom) > 0 {
+			t.Fatalf("expected no first seen from without the option, actual: %s", r.FirstSeenFrom)
+		}
+		referrers[strings.TrimPrefix(r.URL, server.URL)] = r.Referrer
+	}
+
+	expected := map[string]string{
+		"":         "",
+		"/a":       server.URL,
+		"/b":       server.URL,
+		"/a/deep":  server.URL + "/a",
+		"/b/deep":  server.URL + "/b",
+		"/missing": server.URL + "/a/deep",
+	}
+	if len(referrers) != len(expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, referrers)
+	}
+
+	for path, referrer := range expected {
+		if referrers[path] != referrer {
+			t.Fatalf("expected: %s, actual: %s for %s", referrer, referrers[path], path)
+		}
+	}
+}
+
+func TestCrawlMaxTotalBytes(t *testing.T) {
+	padding := strings.Repeat("x", 100)
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>` + padding,
+		"/a": `<a href="/b">b</a>` + padding,
+		"/b": `<a href="/c">c</a>` + padding,
+		"/c": padding,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, MaxTotalBytes: 150})
+	c.Crawl(server.URL)
+
+	if len(c.result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(c.result))
+	}
+
+	if c.parser.DownloadedBytes() <= 150 {
+		t.Fatalf("expected more than %d bytes, actual: %d", 150, c.parser.DownloadedBytes())
+	}
+}
+
+func TestCrawlRadiusCountsBackLinks(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `<a href="/b">b</a>`,
+		"/b": `<a href="/">home</a><a href="/c">c</a>`,
+		"/c": `<a href="/d">d</a>`,
+		"/d": `d`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, Radius: 2})
+	c.Crawl(server.URL)
+
+	crawled := make(map[string]bool)
+	for _, r := range c.result {
+		crawled[strings.TrimPrefix(r.URL, server.URL)] = true
+	}
+
+	for _, path := range []string{"", "/a", "/b", "/c"} {
+		if !crawled[path] {
+			t.Fatalf("expected %s to be crawled, actual: %v", path, crawled)
+		}
+	}
+
+	if crawled["/d"] {
+		t.Fatal("expected /d beyond radius not to be crawled")
+	}
+}
+
+func TestCrawlCheckAssets(t *testing.T) {
+	methods := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/style.css":
+			methods <- r.Method
+			w.Header().Set("Content-Length", "12")
+		case "/logo.png":
+			methods <- r.Method
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="stylesheet" href="/style.css"><img src="/logo.png"><a href="/style.css">css</a>`)
+		}
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, CheckAssets: true, IgnoredExtensions: []string{".css"}})
+	c.Crawl(server.URL)
+	close(methods)
+
+	for m := range methods {
+		if m != http.MethodHead {
+			t.Fatalf("expected method: %s, actual method: %s", http.MethodHead, m)
+		}
+	}
+
+	assets := make(map[string]Result)
+	for _, r := range c.result {
+		if r.Asset {
+			assets[strings.TrimPrefix(r.URL, server.URL)] = r
+		}
+	}
+
+	if len(assets) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(assets))
+	}
+
+	if assets["/style.css"].Status != http.StatusOK || assets["/style.css"].Size != 12 {
+		t.Fatalf("expected: %d %d, actual: %d %d", http.StatusOK, 12, assets["/style.css"].Status, assets["/style.css"].Size)
+	}
+
+	if assets["/logo.png"].Status != http.StatusNotFound {
+		t.Fatalf("expected status: %d, actual status: %d", http.StatusNotFound, assets["/logo.png"].Status)
+	}
+
+	if c.visited.Size() != 1 {
+		t.Fatalf("expected visited: %d, actual visited: %d", 1, c.visited.Size())
+	}
+}
+
+func TestCrawlCheckInternalLinks(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/missing">missing</a><a href="/b">b</a>`,
+		"/b": `<a href="/missing">missing</a>`,
+	})
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, CheckInternalLinks: true, OutputWriter: &buffer})
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if outcome.BrokenLinks != 1 || outcome.ExitCode() != 1 {
+		t.Fatalf("expected broken: %d, actual broken: %d", 1, outcome.BrokenLinks)
+	}
+
+	broken := c.BrokenLinks()[0]
+	if broken.URL != server.URL+"/missing" || broken.Status != http.StatusNotFound {
+		t.Fatalf("expected: %s %d, actual: %s %d", server.URL+"/missing", http.StatusNotFound, broken.URL, broken.Status)
+	}
+
+	expected := []string{server.URL + "/a", server.URL + "/b"}
+	if !slices.Equal(broken.ReferencedBy, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, broken.ReferencedBy)
+	}
+
+	if !strings.Contains(buffer.String(), "    referenced by "+server.URL+"/b") {
+		t.Fatalf("expected the report to list %s, actual: %q", server.URL+"/b", buffer.String())
+	}
+}
+
+func TestCrawlReportReturnsWriteError(t *testing.T) {
+	server := newTestSite(map[string]string{"/": `<a href="/missing">missing</a>`})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, CheckInternalLinks: true, OutputWriter: failingWriter{}})
+	if _, err := c.Crawl(server.URL); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestCrawlRecordExternal(t *testing.T) {
+	var hits atomic.Int32
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer external.Close()
+
+	server := newTestSite(map[string]string{
+		"/":      fmt.Sprintf(`<a href="/about">about</a><a href="%s/page">external</a>`, external.URL),
+		"/about": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, RecordExternal: true})
+	if _, err := c.Crawl(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(c.result))
+	}
+
+	for _, r := range c.result {
+		expected := []string{}
+		if r.URL == server.URL {
+			expected = []string{external.URL + "/page"}
+		}
+
+		if !slices.Equal(r.ExternalLinks, expected) {
+			t.Fatalf("expected: %v, actual: %v", expected, r.ExternalLinks)
+		}
+	}
+
+	if hits.Load() != 0 {
+		t.Fatalf("expected external hits: %d, actual: %d", 0, hits.Load())
+	}
+
+	for i, r := range c.Results() {
+		if len(r.ExternalLinks) > 0 {
+			r.ExternalLinks[0] = "changed"
+			if c.result[i].ExternalLinks[0] == "changed" {
+				t.Fatal("expected results to be a copy")
+			}
+		}
+	}
+}
+
+func TestCrawlBrokenLinks(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `<a href="/missing">missing</a>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2})
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if outcome.BrokenLinks != 0 {
+		t.Fatalf("expected broken: %d, actual broken: %d", 0, outcome.BrokenLinks)
+	}
+
+	links := c.BrokenLinks()
+	if len(links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(links))
+	}
+
+	if links[0].URL != server.URL+"/missing" || links[0].Status != http.StatusNotFound {
+		t.Fatalf("expected: %s %d, actual: %s %d", server.URL+"/missing", http.StatusNotFound, links[0].URL, links[0].Status)
+	}
+
+	if links[0].Referrer != server.URL+"/a" {
+		t.Fatalf("expected referrer: %s, actual referrer: %s", server.URL+"/a", links[0].Referrer)
+	}
+}
+
+func TestCrawlOutputWriter(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `<p>a</p>`,
+	})
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Json, OutputWriter: &buffer})
+	c.Crawl(server.URL)
+
+	var results []Result
+	if err := json.Unmarshal(buffer.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(results))
+	}
+
+	buffer.Reset()
+	c = getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Jsonl, OutputWriter: &buffer})
+	c.Crawl(server.URL)
+
+	if lines := strings.Count(buffer.String(), "\n"); lines != 2 {
+		t.Fatalf("expected lines: %d, actual lines: %d", 2, lines)
+	}
+}
+
+func TestCrawlOnResult(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/b">b</a>`,
+		"/b": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	var calls []string
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 4, OnResult: func(r Result) {
+		calls = append(calls, r.URL)
+	}})
+	c.Crawl(server.URL)
+
+	slices.Sort(calls)
+	expected := []string{server.URL, server.URL + "/a", server.URL + "/b"}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, calls)
+	}
+}
+
+func TestCrawlClassifyAuthRequired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/account":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/admin":
+			w.WriteHeader(http.StatusForbidden)
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			fmt.Fprint(w, `<a href="/account">account</a><a href="/admin">admin</a><a href="/missing">missing</a>`)
+		}
+	}))
+	defer server.Close()
+
+	for _, classify := range []bool{false, true} {
+		c := getTestCrawler(CrawlerOptions{
+			MaxWorkers:           1,
+			FailOn:               getTestFailOn(),
+			CheckInternalLinks:   true,
+			ClassifyAuthRequired: classify,
+		})
+		outcome, err := c.Crawl(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := 3
+		if classify {
+			expected = 1
+		}
+
+		if outcome.Failures != expected || outcome.BrokenLinks != expected {
+			t.Fatalf("expected: %d, actual failures: %d, actual broken: %d", expected, outcome.Failures, outcome.BrokenLinks)
+		}
+
+		for _, r := range c.result {
+			if r.AuthRequired != (classify && isAuthRequired(r.Status)) {
+				t.Fatalf("expected authRequired: %t, actual authRequired: %t for %s", !r.AuthRequired, r.AuthRequired, r.URL)
+			}
+		}
+	}
+}
+
+func TestCrawlMaxDepth(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `<a href="/b">b</a>`,
+		"/b": `<a href="/c">c</a>`,
+		"/c": ``,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, MaxDepth: 1})
+	c.Crawl(server.URL)
+
+	if len(c.result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(c.result))
+	}
+
+	for _, r := range c.result {
+		if r.Depth > 1 {
+			t.Fatalf("expected depth <= %d, actual depth: %d for %s", 1, r.Depth, r.URL)
+		}
+	}
+
+	if c.visited.Has(server.URL + "/b") {
+		t.Fatalf("expected %s not to be crawled", server.URL+"/b")
+	}
+}
+
+func TestCrawlMaxPages(t *testing.T) {
+	pages := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		pages[fmt.Sprintf("/%d", i)] = fmt.Sprintf(`<a href="/%d">next</a><a href="/%d">skip</a>`, i+1, i+2)
+	}
+	pages["/"] = `<a href="/0">0</a>`
+	server := newTestSite(pages)
+	defer server.Close()
+
+	for _, workers := range []int{1, 4} {
+		c := getTestCrawler(CrawlerOptions{MaxWorkers: workers, MaxPages: 3})
+		outcome, err := c.Crawl(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(c.result) > 3 || outcome.Pages > 3 {
+			t.Fatalf("expected at most: %d, actual results: %d, actual pages: %d", 3, len(c.result), outcome.Pages)
+		}
+
+		if workers == 1 && len(c.result) != 3 {
+			t.Fatalf("expected len: %d, actual len: %d", 3, len(c.result))
+		}
+	}
+}
+
+func TestCrawlMaxDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `<a href="%s/next">next</a>`, strings.TrimSuffix(r.URL.Path, "/"))
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, MaxDuration: 300 * time.Millisecond})
+
+	start := time.Now()
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected to return within: %s, actual: %s", 2*time.Second, elapsed)
+	}
+
+	if outcome.Pages <= 0 || len(c.result) <= 0 {
+		t.Fatalf("expected partial results, actual results: %d, actual pages: %d", len(c.result), outcome.Pages)
+	}
+}
+
+func TestCrawlReturnsOutputError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{
+		MaxWorkers:   1,
+		OutputFormat: Output_Json,
+		OutputFile:   filepath.Join(t.TempDir(), "missing", "results"),
+	})
+
+	if _, err := c.Crawl(server.URL); err == nil {
+		t.Fatalf("expected an error writing to a missing directory")
+	}
+}
+
+func TestCrawlInvalidSeed(t *testing.T) {
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 4})
+	before := runtime.NumGoroutine()
+
+	if _, err := c.Crawl("://monzo.com"); err == nil {
+		t.Fatalf("expected an error for a malformed seed url")
+	}
+
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestCrawlDoesNotLeakGoroutines(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/b">b</a><a href="/c">c</a>`,
+		"/b": `<a href="/a">a</a>`,
+		"/c": `c`,
+	})
+	defer server.Close()
+
+	before := runtime.NumGoroutine()
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 4})
+	if _, err := c.Crawl(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestCrawlContextCancels(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fmt.Fprint(w, `<a href="/slow">slow</a>`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, RequestDeadline: 30})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	c.CrawlContext(ctx, server.URL)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected to return within: %s, actual: %s", 2*time.Second, elapsed)
+	}
+}
+
+func TestCrawlResults(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/">home</a>`,
+		"/b": ``,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, OutputFormat: Output_Xml})
+	c.Crawl(server.URL)
+
+	results := c.Results()
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, strings.TrimPrefix(r.URL, server.URL))
+	}
+	slices.Sort(urls)
+
+	expected := []string{"", "/a", "/b"}
+	if !slices.Equal(urls, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, urls)
+	}
+
+	results[0].URL = "changed"
+	if len(results[0].Links) > 0 {
+		results[0].Links[0] = "changed"
+	}
+	if c.result[0].URL == "changed" || (len(c.result[0].Links) > 0 && c.result[0].Links[0] == "changed") {
+		t.Fatal("expected results to be a copy")
+	}
+
+	if !strings.Contains(resultString(t, c), "<crawlerResult ") {
+		t.Fatalf("expected xml elements named %s", "crawlerResult")
+	}
+}
+
+func TestCrawlRecordsTitle(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<title> Home </title><a href="/a">a</a>`,
+		"/a": `no title`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Json})
+	c.Crawl(server.URL)
+
+	expected := map[string]string{server.URL: "Home", server.URL + "/a": ""}
+	for _, r := range c.Results() {
+		if r.Title != expected[r.URL] {
+			t.Fatalf("expected: %q, actual: %q for %s", expected[r.URL], r.Title, r.URL)
+		}
+	}
+
+	if !strings.Contains(resultString(t, c), `"title": "Home"`) {
+		t.Fatalf("expected the title in the json output")
+	}
+}
+
+func TestCrawlRecordsResponseTime(t *testing.T) {
+	delay := 100 * time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/slow" {
+			time.Sleep(delay)
+		}
+		fmt.Fprint(w, `<a href="/slow">slow</a>`)
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Csv})
+	c.Crawl(server.URL)
+
+	var found bool
+	for _, r := range c.Results() {
+		if r.URL != server.URL+"/slow" {
+			continue
+		}
+
+		found = true
+		if r.ResponseTime < delay {
+			t.Fatalf("expected at least: %s, actual: %s", delay, r.ResponseTime)
+		}
+	}
+
+	if !found {
+		t.Fatalf("expected %s to be crawled", server.URL+"/slow")
+	}
+
+	if !strings.Contains(resultString(t, c), "response_time_ms") {
+		t.Fatalf("expected a %s column", "response_time_ms")
+	}
+}
+
+func TestUiSpinnerGrowsWithWorkers(t *testing.T) {
+	ui, err := newUi(CrawlerOptions{MaxWorkers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, s := range ui.spinners {
+			s.Stop()
+		}
+	}()
+
+	if len(ui.spinners) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(ui.spinners))
+	}
+
+	spinner, err := ui.spinner(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ui.spinners) != 5 || spinner != ui.spinners[4] {
+		t.Fatalf("expected len: %d, actual len: %d", 5, len(ui.spinners))
+	}
+}
+
+func newTestSite(pages map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, body)
+	}))
+}
+
+func getTestFailOn() []StatusRange {
+	failOn, err := ParseStatusRanges(DefaultFailOn)
+	if err != nil {
+		panic(err)
+	}
+	return failOn
+}
+
+func getTestCrawler(opts CrawlerOptions) *Crawler {
+	if opts.RequestDeadline <= 0 {
+		opts.RequestDeadline = 5
+	}
+
+	c, err := NewCrawler(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func resultString(t *testing.T, c *Crawler) string {
+	t.Helper()
+
+	results, err := c.getResultString()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return results
+}
+
+func TestHandleSignals(t *testing.T) {
+	probe := make(chan os.Signal, 1)
+	signal.Notify(probe, syscall.SIGTERM)
+	defer signal.Stop(probe)
+
+	for _, handle := range []bool{false, true} {
+		c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, HandleSignals: handle})
+		if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+			t.Fatal(err)
+		}
+		<-probe
+
+		received := false
+		select {
+		case <-c.quit:
+			received = true
+		case <-time.After(100 * time.Millisecond):
+		}
+		signal.Stop(c.quit)
+
+		if received != handle {
+			t.Fatalf("expected signal received: %v, actual: %v", handle, received)
+		}
+	}
+}
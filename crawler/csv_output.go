@@ -0,0 +1,26 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+)
+
+var csvHeader = []string{"url", "status", "error", "link_count", "response_time_ms"}
+
+func csvResults(results []Result) (string, error) {
+	var builder strings.Builder
+	w := csv.NewWriter(&builder)
+	if err := w.Write(csvHeader); err != nil {
+		return "", err
+	}
+
+	for _, r := range results {
+		if err := w.Write([]string{r.URL, strconv.Itoa(r.Status), r.Error, strconv.Itoa(r.Count), strconv.FormatInt(r.ResponseTime.Milliseconds(), 10)}); err != nil {
+			return "", err
+		}
+	}
+
+	w.Flush()
+	return builder.String(), w.Error()
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"encoding/csv"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetResultStringCsv(t *testing.T) {
+	c := &Crawler{
+		opts: CrawlerOptions{OutputFormat: Output_Csv},
+		result: []Result{
+			{URL: "https://monzo.com", Status: 200, Count: 2, ResponseTime: 1500 * time.Millisecond},
+			{URL: "https://monzo.com/a,b", Status: 500, Error: `read "body": connection reset`},
+		},
+	}
+
+	records, err := csv.NewReader(strings.NewReader(resultString(t, c))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"url", "status", "error", "link_count", "response_time_ms"},
+		{"https://monzo.com", "200", "", "2", "1500"},
+		{"https://monzo.com/a,b", "500", `read "body": connection reset`, "0", "0"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected len: %d, actual len: %d", len(expected), len(records))
+	}
+
+	for i := range expected {
+		if !slices.Equal(records[i], expected[i]) {
+			t.Fatalf("expected: %q, actual: %q", expected[i], records[i])
+		}
+	}
+
+	c.opts.OutputFile = "results"
+	if c.outputFilename() != "results.csv" {
+		t.Fatalf("expected: %s, actual: %s", "results.csv", c.outputFilename())
+	}
+}
@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type ExternalLink struct {
+	URL      string
+	Status   int
+	Error    string
+	Referrer string
+}
+
+func (c *Crawler) checkExternal(input string, links []string) {
+	for _, link := range links {
+		if c.stopping.Load() {
+			return
+		}
+
+		if !c.claimExternal(link) {
+			continue
+		}
+
+		check, err := c.parser.CheckExternal(c.ctx, link)
+		c.countBytes()
+		external := ExternalLink{
+			URL:      link,
+			Status:   check.StatusCode,
+			Referrer: input,
+		}
+
+		if err != nil {
+			if !c.opts.Interactive {
+				hclog.Default().Error("external link check failed", "input", link, "page", input, "error", err)
+			}
+			external.Error = err.Error()
+		}
+
+		c.externalLock.Lock()
+		c.external[link] = external
+		c.externalLock.Unlock()
+	}
+}
+
+func (c *Crawler) claimExternal(link string) bool {
+	c.externalLock.Lock()
+	defer c.externalLock.Unlock()
+
+	if _, ok := c.external[link]; ok {
+		return false
+	}
+	c.external[link] = ExternalLink{URL: link}
+	return true
+}
+
+func (c *Crawler) ExternalLinks() map[string]ExternalLink {
+	c.externalLock.Lock()
+	defer c.externalLock.Unlock()
+
+	links := make(map[string]ExternalLink, len(c.external))
+	for url, link := range c.external {
+		links[url] = link
+	}
+	return links
+}
+
+func (c *Crawler) externalReport() string {
+	links := c.ExternalLinks()
+	urls := make([]string, 0, len(links))
+	for url := range links {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "External links: %d\n", len(urls))
+	for _, url := range urls {
+		l := links[url]
+		if len(l.Error) <= 0 && l.Status < http.StatusBadRequest {
+			fmt.Fprintf(&builder, "%d %s\n", l.Status, l.URL)
+			continue
+		}
+
+		reason := l.Error
+		if len(reason) <= 0 {
+			reason = http.StatusText(l.Status)
+		}
+		fmt.Fprintf(&builder, "%d %s (%s)\n", l.Status, l.URL, reason)
+	}
+
+	return builder.String()
+}
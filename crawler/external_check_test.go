@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCheckExternal(t *testing.T) {
+	var heads atomic.Int32
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+
+		if r.URL.Path == "/dead" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+
+	links := fmt.Sprintf(`<a href="%[1]s/alive">alive</a><a href="%[1]s/dead">dead</a>`, external.URL)
+	server := newTestSite(map[string]string{
+		"/":      `<a href="/about">about</a>` + links,
+		"/about": links,
+	})
+	defer server.Close()
+
+	var buffer bytes.Buffer
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, CheckExternal: true, OutputWriter: &buffer})
+	if _, err := c.Crawl(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checked := c.ExternalLinks()
+	if len(checked) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(checked))
+	}
+
+	if status := checked[external.URL+"/dead"].Status; status != http.StatusNotFound {
+		t.Fatalf("expected: %d, actual: %d", http.StatusNotFound, status)
+	}
+
+	if status := checked[external.URL+"/alive"].Status; status != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d", http.StatusOK, status)
+	}
+
+	if heads.Load() != 2 {
+		t.Fatalf("expected heads: %d, actual heads: %d", 2, heads.Load())
+	}
+
+	if !strings.Contains(buffer.String(), "404 "+external.URL+"/dead (Not Found)") {
+		t.Fatalf("expected the report to list %s, actual: %q", external.URL+"/dead", buffer.String())
+	}
+}
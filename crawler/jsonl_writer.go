@@ -0,0 +1,63 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type jsonlWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	lock    sync.Mutex
+}
+
+func newJsonlWriter(filename string, resume bool, output io.Writer) (*jsonlWriter, error) {
+	var file *os.File
+	if len(filename) > 0 {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if resume {
+			hclog.Default().Info("appending to existing jsonl output", "filename", filename)
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+
+		f, err := os.OpenFile(filename, flags, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+		output = f
+	}
+
+	buffer := bufio.NewWriter(output)
+	return &jsonlWriter{file: file, buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+}
+
+func (w *jsonlWriter) write(r Result) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.encoder.Encode(r); err != nil {
+		return err
+	}
+	return w.buffer.Flush()
+}
+
+func (w *jsonlWriter) close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.buffer.Flush(); err != nil {
+		return err
+	}
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
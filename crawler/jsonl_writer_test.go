@@ -0,0 +1,49 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCrawlJsonlOutput(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `<a href="/">home</a>`,
+		"/b": `<p>b</p>`,
+	})
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "results")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, OutputFormat: Output_Jsonl, OutputFile: output})
+	c.Crawl(server.URL)
+
+	file, err := os.Open(output + ".jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("expected each line to unmarshal, actual: %v in %s", err, scanner.Text())
+		}
+		urls = append(urls, r.URL)
+	}
+
+	slices.Sort(urls)
+	expected := []string{server.URL, server.URL + "/a", server.URL + "/b"}
+	if !slices.Equal(urls, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, urls)
+	}
+
+	if len(c.result) != 0 {
+		t.Fatalf("expected results not to be buffered, actual len: %d", len(c.result))
+	}
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type BrokenLink struct {
+	URL          string
+	Status       int
+	Error        string
+	Referrer     string
+	ReferencedBy []string
+}
+
+func isBroken(result Result) bool {
+	return !result.AuthRequired && (len(result.Error) > 0 || result.Status >= http.StatusBadRequest)
+}
+
+func (c *Crawler) addInbound(source string, links []string) {
+	c.inboundLock.Lock()
+	defer c.inboundLock.Unlock()
+
+	for _, l := range links {
+		c.inbound[l] = append(c.inbound[l], source)
+	}
+}
+
+func (c *Crawler) BrokenLinks() []BrokenLink {
+	c.resultLock.Lock()
+	broken := make([]Result, len(c.broken))
+	copy(broken, c.broken)
+	c.resultLock.Unlock()
+
+	c.referrerLock.Lock()
+	defer c.referrerLock.Unlock()
+	c.inboundLock.Lock()
+	defer c.inboundLock.Unlock()
+
+	links := make([]BrokenLink, len(broken))
+	for i, result := range broken {
+		referencedBy := distinctStrings(c.inbound[result.URL])
+		sort.Strings(referencedBy)
+		links[i] = BrokenLink{
+			URL:          result.URL,
+			Status:       result.Status,
+			Error:        result.Error,
+			Referrer:     c.referrers[result.URL],
+			ReferencedBy: referencedBy,
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].URL < links[j].URL })
+	return links
+}
+
+func (c *Crawler) brokenLinkCount() int {
+	if !c.opts.CheckInternalLinks {
+		return 0
+	}
+
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+	return len(c.broken)
+}
+
+func (c *Crawler) linkReport() string {
+	links := c.BrokenLinks()
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Broken internal links: %d\n", len(links))
+	for _, l := range links {
+		reason := l.Error
+		if len(reason) <= 0 {
+			reason = http.StatusText(l.Status)
+		}
+
+		fmt.Fprintf(&builder, "%d %s (%s)\n", l.Status, l.URL, reason)
+		for _, r := range l.ReferencedBy {
+			fmt.Fprintf(&builder, "    referenced by %s\n", r)
+		}
+	}
+
+	return builder.String()
+}
+
+func distinctStrings(values []string) []string {
+	set := make(map[string]bool, len(values))
+	var distinct []string
+	for _, v := range values {
+		if set[v] {
+			continue
+		}
+		set[v] = true
+		distinct = append(distinct, v)
+	}
+	return distinct
+}
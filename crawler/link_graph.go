@@ -0,0 +1,130 @@
+package crawler
+
+import "sync"
+
+type linkGraph struct {
+	edges    map[string]map[string]struct{}
+	root     string
+	distance map[string]int
+	lock     sync.RWMutex
+}
+
+func (g *linkGraph) setRoot(root string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.root = root
+	g.distance = g.bfs(root)
+}
+
+func (g *linkGraph) addEdges(source string, targets []string) map[string]int {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.edges == nil {
+		g.edges = make(map[string]map[string]struct{})
+	}
+
+	for _, t := range targets {
+		if t == source {
+			continue
+		}
+		g.link(source, t)
+		g.link(t, source)
+	}
+
+	if g.distance == nil {
+		return nil
+	}
+
+	changed := make(map[string]int)
+	var queue []string
+	relax := func(node string, d int) {
+		if existing, ok := g.distance[node]; ok && existing <= d {
+			return
+		}
+		g.distance[node] = d
+		changed[node] = d
+		queue = append(queue, node)
+	}
+
+	if d, ok := g.distance[source]; ok {
+		for _, t := range targets {
+			relax(t, d+1)
+		}
+	}
+
+	for _, t := range targets {
+		if d, ok := g.distance[t]; ok {
+			relax(source, d+1)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for n := range g.edges[current] {
+			relax(n, g.distance[current]+1)
+		}
+	}
+	return changed
+}
+
+func (g *linkGraph) link(from string, to string) {
+	neighbours, ok := g.edges[from]
+	if !ok {
+		neighbours = make(map[string]struct{})
+		g.edges[from] = neighbours
+	}
+	neighbours[to] = struct{}{}
+}
+
+func (g *linkGraph) distanceTo(node string) (int, bool) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	d, ok := g.distance[node]
+	return d, ok
+}
+
+func (g *linkGraph) distances(root string) map[string]int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.bfs(root)
+}
+
+func (g *linkGraph) bfs(root string) map[string]int {
+	distances := map[string]int{root: 0}
+	queue := []string{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for n := range g.edges[current] {
+			if _, ok := distances[n]; ok {
+				continue
+			}
+
+			distances[n] = distances[current] + 1
+			queue = append(queue, n)
+		}
+	}
+	return distances
+}
+
+func (g *linkGraph) snapshot() map[string][]string {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	edges := make(map[string][]string, len(g.edges))
+	for from, neighbours := range g.edges {
+		for to := range neighbours {
+			edges[from] = append(edges[from], to)
+		}
+	}
+	return edges
+}
+
+func (g *linkGraph) restore(edges map[string][]string) {
+	for from, neighbours := range edges {
+		g.addEdges(from, neighbours)
+	}
+}
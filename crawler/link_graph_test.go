@@ -0,0 +1,70 @@
+package crawler
+
+import "testing"
+
+func TestLinkGraphDistancesUndirected(t *testing.T) {
+	var g linkGraph
+	g.addEdges("seed", []string{"a"})
+	g.addEdges("a", []string{"b"})
+	g.addEdges("b", []string{"c"})
+
+	distances := g.distances("seed")
+	expected := map[string]int{"seed": 0, "a": 1, "b": 2, "c": 3}
+	for node, d := range expected {
+		if distances[node] != d {
+			t.Fatalf("expected %s at distance %d, actual: %d", node, d, distances[node])
+		}
+	}
+
+	g.addEdges("c", []string{"seed"})
+	if distances := g.distances("seed"); distances["c"] != 1 || distances["b"] != 2 {
+		t.Fatalf("expected back-link to shorten distances, actual: %v", distances)
+	}
+}
+
+func TestLinkGraphDistancesUnreachable(t *testing.T) {
+	var g linkGraph
+	g.addEdges("x", []string{"y"})
+
+	distances := g.distances("seed")
+	if _, ok := distances["x"]; ok {
+		t.Fatal("expected x to be unreachable")
+	}
+}
+
+func TestLinkGraphIncrementalDistances(t *testing.T) {
+	var g linkGraph
+	g.setRoot("seed")
+	g.addEdges("seed", []string{"a"})
+	g.addEdges("a", []string{"b"})
+	changed := g.addEdges("b", []string{"c"})
+
+	if changed["c"] != 3 || len(changed) != 1 {
+		t.Fatalf("expected only c to change, actual: %v", changed)
+	}
+
+	changed = g.addEdges("c", []string{"seed"})
+	if changed["c"] != 1 {
+		t.Fatalf("expected back-link to shorten c, actual: %v", changed)
+	}
+
+	for node, expected := range g.distances("seed") {
+		if d, ok := g.distanceTo(node); !ok || d != expected {
+			t.Fatalf("expected %s at distance %d, actual: %d", node, expected, d)
+		}
+	}
+}
+
+func TestLinkGraphSetRootAfterRestore(t *testing.T) {
+	var g linkGraph
+	g.restore(map[string][]string{"seed": {"a"}, "a": {"b"}})
+	g.setRoot("seed")
+
+	if d, ok := g.distanceTo("b"); !ok || d != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, d)
+	}
+
+	if _, ok := g.distanceTo("x"); ok {
+		t.Fatal("expected x to be unreachable")
+	}
+}
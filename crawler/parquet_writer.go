@@ -0,0 +1,103 @@
+package crawler
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/parquet-go/parquet-go"
+)
+
+const ParquetRowGroupSize = 1000
+
+type parquetRow struct {
+	URL           string   `parquet:"url"`
+	Status        int32    `parquet:"status"`
+	Error         string   `parquet:"error"`
+	SoftError     string   `parquet:"soft_error"`
+	DuplicateOf   string   `parquet:"duplicate_of"`
+	FirstSeenFrom string   `parquet:"first_seen_from"`
+	Depth         int32    `parquet:"depth"`
+	LinkCount     int32    `parquet:"link_count"`
+	Links         []string `parquet:"links,list"`
+}
+
+type parquetWriter struct {
+	file   *os.File
+	writer *parquet.GenericWriter[parquetRow]
+	rows   int
+	lock   sync.Mutex
+}
+
+func newParquetWriter(filename string, resume bool) (*parquetWriter, error) {
+	var existing []parquetRow
+	if resume {
+		rows, err := parquet.ReadFile[parquetRow](filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		existing = rows
+		hclog.Default().Info("appending to existing parquet output", "filename", filename, "rows", len(existing))
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &parquetWriter{
+		file:   file,
+		writer: parquet.NewGenericWriter[parquetRow](file),
+	}
+
+	for _, row := range existing {
+		if err := w.writeRow(row); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *parquetWriter) write(r Result) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	row := parquetRow{
+		URL:           r.URL,
+		Status:        int32(r.Status),
+		Error:         r.Error,
+		SoftError:     r.SoftError,
+		DuplicateOf:   r.DuplicateOf,
+		FirstSeenFrom: r.FirstSeenFrom,
+		Depth:         int32(r.Depth),
+		LinkCount:     int32(r.Count),
+		Links:         r.Links,
+	}
+
+	return w.writeRow(row)
+}
+
+func (w *parquetWriter) writeRow(row parquetRow) error {
+	if _, err := w.writer.Write([]parquetRow{row}); err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%ParquetRowGroupSize == 0 {
+		return w.writer.Flush()
+	}
+	return nil
+}
+
+func (w *parquetWriter) close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.writer.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,115 @@
+package crawler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestParquetWriterWritesRows(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.parquet")
+	writer, err := newParquetWriter(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []Result{
+		{URL: "https://monzo.com", Status: 200, Count: 2, Links: []string{"https://monzo.com/about", "https://monzo.com/blog"}},
+		{URL: "https://monzo.com/about", Status: 404, Error: "not found", Depth: 1},
+	}
+
+	for _, r := range results {
+		if err := writer.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := parquet.ReadFile[parquetRow](filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected rows: %d, actual rows: %d", 2, len(rows))
+	}
+
+	if len(rows[0].Links) != 2 || rows[0].Links[1] != "https://monzo.com/blog" {
+		t.Fatalf("expected: %v, actual: %v", results[0].Links, rows[0].Links)
+	}
+
+	if rows[1].Status != 404 || rows[1].Depth != 1 || rows[1].Error != "not found" {
+		t.Fatalf("expected: %v, actual: %v", results[1], rows[1])
+	}
+}
+
+func TestParquetWriterFlushesRowGroups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.parquet")
+	writer, err := newParquetWriter(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < ParquetRowGroupSize+1; i++ {
+		if err := writer.write(Result{URL: "https://monzo.com", Status: 200}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.RowGroups()) != 2 {
+		t.Fatalf("expected row groups: %d, actual row groups: %d", 2, len(file.RowGroups()))
+	}
+}
+
+func TestParquetWriterResumeKeepsExistingRows(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.parquet")
+	for i, resume := range []bool{false, true} {
+		writer, err := newParquetWriter(filename, resume)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.write(Result{URL: fmt.Sprintf("https://monzo.com/%d", i), Status: 200}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := parquet.ReadFile[parquetRow](filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected rows: %d, actual rows: %d", 2, len(rows))
+	}
+}
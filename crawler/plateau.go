@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const DefaultPlateauWindow = 50
+
+type plateauWindow struct {
+	discovered []int
+	linked     []int
+	next       int
+	full       bool
+	lock       sync.Mutex
+}
+
+func (w *plateauWindow) observe(discovered int, linked int, size int) (float64, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.linked) != size {
+		w.discovered = make([]int, size)
+		w.linked = make([]int, size)
+		w.next = 0
+		w.full = false
+	}
+
+	w.discovered[w.next] = discovered
+	w.linked[w.next] = linked
+	w.next = (w.next + 1) % size
+	if w.next == 0 {
+		w.full = true
+	}
+
+	if !w.full {
+		return 0, false
+	}
+
+	var discoveredTotal, linkedTotal int
+	for i := range w.linked {
+		discoveredTotal += w.discovered[i]
+		linkedTotal += w.linked[i]
+	}
+
+	if linkedTotal <= 0 {
+		return 0, true
+	}
+	return float64(discoveredTotal) / float64(linkedTotal), true
+}
+
+func (c *Crawler) observePlateau(links []string) {
+	var discovered int
+	for _, l := range links {
+		if !c.cache.Has(l) {
+			discovered++
+		}
+	}
+
+	size := c.opts.PlateauWindow
+	if size <= 0 {
+		size = DefaultPlateauWindow
+	}
+
+	ratio, full := c.plateau.observe(discovered, len(links), size)
+	if !full || ratio >= c.opts.PlateauThreshold || c.stopping.Load() {
+		return
+	}
+
+	hclog.Default().Info("link discovery plateaued, stopping crawl",
+		"ratio", ratio,
+		"threshold", c.opts.PlateauThreshold,
+		"window", size,
+	)
+	c.stop()
+}
@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPlateauWindowSlides(t *testing.T) {
+	var w plateauWindow
+	if _, full := w.observe(10, 10, 3); full {
+		t.Fatalf("expected the window to fill after %d pages", 3)
+	}
+	w.observe(5, 10, 3)
+
+	ratio, full := w.observe(0, 10, 3)
+	if !full || ratio != 0.5 {
+		t.Fatalf("expected: %f, actual: %f", 0.5, ratio)
+	}
+
+	ratio, _ = w.observe(1, 10, 3)
+	if ratio != 0.2 {
+		t.Fatalf("expected: %f, actual: %f", 0.2, ratio)
+	}
+}
+
+func TestCrawlStopsOnPlateau(t *testing.T) {
+	pages := make(map[string]string)
+	var links strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&links, `<a href="/%d">%d</a>`, i, i)
+	}
+	pages["/"] = links.String()
+	for i := 0; i < 20; i++ {
+		pages[fmt.Sprintf("/%d", i)] = links.String()
+	}
+
+	server := newTestSite(pages)
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, PlateauThreshold: 0.1, PlateauWindow: 3})
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if outcome.Pages >= 21 {
+		t.Fatalf("expected fewer than %d pages, actual: %d", 21, outcome.Pages)
+	}
+}
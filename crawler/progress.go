@@ -0,0 +1,27 @@
+package crawler
+
+type ProgressEvent struct {
+	Visited  int
+	Total    int
+	Frontier int
+	URL      string
+}
+
+func (c *Crawler) progress(visited int, total int) {
+	if c.opts.Progress == nil {
+		return
+	}
+
+	current, _ := c.current.Load().(string)
+	event := ProgressEvent{
+		Visited:  visited,
+		Total:    total,
+		Frontier: max(total-visited, 0),
+		URL:      current,
+	}
+
+	select {
+	case c.opts.Progress <- event:
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCrawlProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<a href="/%d">%d</a>`, i, i)
+		}
+	}))
+	defer server.Close()
+
+	progress := make(chan ProgressEvent, 100)
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, Progress: progress})
+	c.Crawl(server.URL)
+	close(progress)
+
+	var events []ProgressEvent
+	for e := range progress {
+		events = append(events, e)
+	}
+
+	if len(events) < 2 {
+		t.Fatalf("expected at least: %d, actual events: %d", 2, len(events))
+	}
+
+	for i := 1; i < len(events); i++ {
+		if events[i].Visited < events[i-1].Visited || events[i].Total < events[i-1].Total {
+			t.Fatalf("expected increasing counts, actual: %+v then %+v", events[i-1], events[i])
+		}
+	}
+
+	last := events[len(events)-1]
+	if last.Visited != 11 || last.Total != 11 || last.Frontier != 0 {
+		t.Fatalf("expected: %d %d %d, actual: %d %d %d", 11, 11, 0, last.Visited, last.Total, last.Frontier)
+	}
+
+	if len(events[0].URL) <= 0 {
+		t.Fatalf("expected the current url to be reported")
+	}
+}
+
+func TestCrawlProgressDoesNotBlock(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a>`,
+		"/a": `a`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, Progress: make(chan ProgressEvent)})
+	outcome, err := c.Crawl(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if outcome.Pages != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, outcome.Pages)
+	}
+}
@@ -0,0 +1,64 @@
+package crawler
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"slices"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+const SitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapUrlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Urls    []sitemapUrl `xml:"url"`
+}
+
+type sitemapUrl struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+func (c *Crawler) sitemap(results []Result) (string, error) {
+	crawled := c.started
+	if crawled.IsZero() {
+		crawled = time.Now()
+	}
+	lastMod := crawled.UTC().Format(time.RFC3339)
+
+	urlSet := sitemapUrlSet{Xmlns: SitemapNamespace}
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if r.Status != http.StatusOK || len(r.Error) > 0 || r.Asset || seen[r.URL] {
+			continue
+		}
+
+		seen[r.URL] = true
+		urlSet.Urls = append(urlSet.Urls, sitemapUrl{Loc: r.URL, LastMod: lastMod})
+	}
+
+	b, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b), nil
+}
+
+func (c *Crawler) sitemapTasks(ctx context.Context, seed string) []crawlTask {
+	urls, err := c.parser.SitemapUrls(ctx, seed+"/sitemap.xml")
+	if err != nil {
+		hclog.Default().Warn("failed to read sitemap, crawling from the seed only", "input", seed, "error", err)
+		return nil
+	}
+
+	urls = slices.DeleteFunc(urls, func(u string) bool { return u == seed })
+	hclog.Default().Debug("seeding crawl from sitemap", "input", seed, "urls", len(urls))
+
+	c.addReferrers(seed, urls)
+	c.cache.AddSlice(urls)
+	return newCrawlTasks(urls, 1, seed)
+}
@@ -0,0 +1,101 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetResultStringSitemap(t *testing.T) {
+	c := &Crawler{
+		opts:    CrawlerOptions{OutputFormat: Output_Sitemap},
+		started: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		result: []Result{
+			{URL: "https://monzo.com", Status: 200},
+			{URL: "https://monzo.com/missing", Status: 404},
+			{URL: "https://monzo.com/broken", Error: "unexpected EOF"},
+			{URL: "https://monzo.com/logo.png", Status: 200, Asset: true},
+			{URL: "https://monzo.com/about?a=1&b=2", Status: 200},
+		},
+	}
+
+	sitemap := resultString(t, c)
+	if !strings.HasPrefix(sitemap, xml.Header) {
+		t.Fatalf("expected an xml header, actual: %q", sitemap)
+	}
+
+	var urlSet sitemapUrlSet
+	if err := xml.Unmarshal([]byte(sitemap), &urlSet); err != nil {
+		t.Fatal(err)
+	}
+
+	if urlSet.XMLName.Local != "urlset" || urlSet.XMLName.Space != SitemapNamespace {
+		t.Fatalf("expected: %s %s, actual: %s %s", "urlset", SitemapNamespace, urlSet.XMLName.Local, urlSet.XMLName.Space)
+	}
+
+	if len(urlSet.Urls) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(urlSet.Urls))
+	}
+
+	if urlSet.Urls[1].Loc != "https://monzo.com/about?a=1&b=2" || urlSet.Urls[1].LastMod != "2024-03-01T09:30:00Z" {
+		t.Fatalf("expected: %s %s, actual: %s %s", "https://monzo.com/about?a=1&b=2", "2024-03-01T09:30:00Z", urlSet.Urls[1].Loc, urlSet.Urls[1].LastMod)
+	}
+
+	if !strings.Contains(sitemap, "about?a=1&amp;b=2") {
+		t.Fatalf("expected escaped ampersands, actual: %q", sitemap)
+	}
+}
+
+func TestSeedFromSitemap(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<sitemapindex xmlns="%s"><sitemap><loc>%s/pages.xml.gz</loc></sitemap></sitemapindex>`, SitemapNamespace, server.URL)
+		case "/pages.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, `<urlset xmlns="%[1]s"><url><loc>%[2]s/a</loc></url><url><loc>%[2]s/b</loc></url><url><loc>https://example.com/c</loc></url></urlset>`, SitemapNamespace, server.URL)
+			gz.Close()
+			w.Write(buf.Bytes())
+		case "/", "/a", "/b":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>no links</p>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, SeedFromSitemap: true, RecordReferrer: true})
+	if _, err := c.Crawl(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, r := range c.result {
+		expected := server.URL
+		if r.URL == server.URL {
+			expected = ""
+		}
+
+		if r.FirstSeenFrom != expected || r.Referrer != expected {
+			t.Fatalf("expected: %s, actual: %s %s for %s", expected, r.FirstSeenFrom, r.Referrer, r.URL)
+		}
+	}
+
+	for _, url := range []string{server.URL, server.URL + "/a", server.URL + "/b"} {
+		if !c.visited.Has(url) {
+			t.Fatalf("expected %s to be crawled", url)
+		}
+	}
+
+	if c.visited.Size() != 3 {
+		t.Fatalf("expected len: %d, actual len: %d", 3, c.visited.Size())
+	}
+}
@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS pages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL UNIQUE,
+	status INTEGER NOT NULL,
+	error TEXT,
+	soft_error TEXT,
+	duplicate_of TEXT,
+	first_seen_from TEXT,
+	depth INTEGER NOT NULL,
+	link_count INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS links (
+	page_id INTEGER NOT NULL REFERENCES pages(id),
+	url TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS links_page_id ON links(page_id);
+CREATE INDEX IF NOT EXISTS links_url ON links(url);
+`
+
+const sqliteUpsertPage = `
+INSERT INTO pages (url, status, error, soft_error, duplicate_of, first_seen_from, depth, link_count)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (url) DO UPDATE SET
+	status = excluded.status,
+	error = excluded.error,
+	soft_error = excluded.soft_error,
+	duplicate_of = excluded.duplicate_of,
+	first_seen_from = excluded.first_seen_from,
+	depth = excluded.depth,
+	link_count = excluded.link_count
+RETURNING id
+`
+
+type resultWriter interface {
+	write(r Result) error
+	close() error
+}
+
+type depthUpdater interface {
+	updateDepth(url string, depth int) error
+}
+
+type sqliteWriter struct {
+	db   *sql.DB
+	lock sync.Mutex
+}
+
+func newSqliteWriter(filename string, resume bool) (*sqliteWriter, error) {
+	if resume {
+		hclog.Default().Info("appending to existing sqlite output", "filename", filename)
+	} else if err := os.Remove(filename); err == nil {
+		hclog.Default().Warn("replaced existing sqlite output", "filename", filename)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteWriter{db: db}, nil
+}
+
+func (w *sqliteWriter) write(r Result) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pageId int64
+	err = tx.QueryRow(sqliteUpsertPage,
+		r.URL, r.Status, r.Error, r.SoftError, r.DuplicateOf, r.FirstSeenFrom, r.Depth, r.Count).Scan(&pageId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM links WHERE page_id = ?", pageId); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO links (page_id, url) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, l := range r.Links {
+		if _, err := stmt.Exec(pageId, l); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (w *sqliteWriter) updateDepth(url string, depth int) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	_, err := w.db.Exec("UPDATE pages SET depth = ? WHERE url = ?", depth, url)
+	return err
+}
+
+func (w *sqliteWriter) close() error {
+	return w.db.Close()
+}
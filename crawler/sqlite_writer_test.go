@@ -0,0 +1,197 @@
+package crawler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSqliteWriterWritesPagesAndLinks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.db")
+	writer, err := newSqliteWriter(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []Result{
+		{URL: "https://monzo.com", Status: 200, Count: 2, Links: []string{"https://monzo.com/about", "https://monzo.com/blog"}},
+		{URL: "https://monzo.com/about", Status: 404, Count: 0},
+	}
+
+	for _, r := range results {
+		if err := writer.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var pages int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pages").Scan(&pages); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 2 {
+		t.Fatalf("expected pages: %d, actual pages: %d", 2, pages)
+	}
+
+	var links int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM links l JOIN pages p ON p.id = l.page_id WHERE p.url = ?",
+		"https://monzo.com").Scan(&links)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if links != 2 {
+		t.Fatalf("expected links: %d, actual links: %d", 2, links)
+	}
+}
+
+func TestSqliteWriterReplacesExistingFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.db")
+	for i := 0; i < 2; i++ {
+		writer, err := newSqliteWriter(filename, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.write(Result{URL: "https://monzo.com", Status: 200}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writer.close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSqliteWriterUpsertsDuplicateUrls(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "crawl.db")
+	writer, err := newSqliteWriter(filename, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []Result{
+		{URL: "https://monzo.com", Status: 500, Error: "boom"},
+		{URL: "https://monzo.com", Status: 200, Count: 1, Links: []string{"https://monzo.com/about"}},
+	}
+
+	for _, r := range results {
+		if err := writer.write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var pages, status int
+	if err := db.QueryRow("SELECT COUNT(*), MAX(status) FROM pages").Scan(&pages, &status); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 1 || status != 200 {
+		t.Fatalf("expected pages: %d status: %d, actual pages: %d status: %d", 1, 200, pages, status)
+	}
+
+	var links int
+	if err := db.QueryRow("SELECT COUNT(*) FROM links").Scan(&links); err != nil {
+		t.Fatal(err)
+	}
+
+	if links != 1 {
+		t.Fatalf("expected links: %d, actual links: %d", 1, links)
+	}
+}
+
+func TestCrawlMaxOutputLimitsSqliteRows(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a": `a`,
+		"/b": `b`,
+	})
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "crawl.db")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: Output_Sqlite, OutputFile: filename, MaxOutput: 2})
+	c.Crawl(server.URL)
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var pages int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pages").Scan(&pages); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages != 2 {
+		t.Fatalf("expected pages: %d, actual pages: %d", 2, pages)
+	}
+}
+
+func TestCrawlSettlesSqliteDepths(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/slow">slow</a><a href="/fast">fast</a>`)
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+			fmt.Fprint(w, `<a href="/target">target</a>`)
+		case "/fast":
+			fmt.Fprint(w, `<a href="/middle">middle</a>`)
+		case "/middle":
+			fmt.Fprint(w, `<a href="/target">target</a>`)
+		}
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "crawl.db")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2, OutputFormat: Output_Sqlite, OutputFile: filename})
+	if _, err := c.Crawl(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var depth int
+	if err := db.QueryRow("SELECT depth FROM pages WHERE url = ?", server.URL+"/target").Scan(&depth); err != nil {
+		t.Fatal(err)
+	}
+
+	if depth != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, depth)
+	}
+
+	if distribution := c.DepthDistribution(); distribution[2] != 2 || distribution[3] != 0 {
+		t.Fatalf("expected: %v, actual: %v", map[int]int{0: 1, 1: 2, 2: 2}, distribution)
+	}
+}
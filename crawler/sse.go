@@ -0,0 +1,129 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const sseClientBuffer = 64
+
+type sseBroker struct {
+	clients map[chan []byte]struct{}
+	lock    sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newSseBroker() *sseBroker {
+	return &sseBroker{
+		clients: make(map[chan []byte]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (b *sseBroker) publish(r Result) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		hclog.Default().Error("failed to marshal sse event", "url", r.URL, "error", err)
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for client := range b.clients {
+		select {
+		case client <- data:
+		default:
+			hclog.Default().Warn("sse client too slow, dropping event", "url", r.URL)
+		}
+	}
+}
+
+func (b *sseBroker) subscribe() chan []byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	client := make(chan []byte, sseClientBuffer)
+	b.clients[client] = struct{}{}
+	return client
+}
+
+func (b *sseBroker) unsubscribe(client chan []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.clients, client)
+}
+
+func (b *sseBroker) size() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.clients)
+}
+
+func (b *sseBroker) close() {
+	b.once.Do(func() { close(b.done) })
+}
+
+func (b *sseBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	client := b.subscribe()
+	defer b.unsubscribe(client)
+	hclog.Default().Debug("sse client connected", "remote", r.RemoteAddr)
+
+	for {
+		select {
+		case data := <-client:
+			fmt.Fprintf(w, "event: result\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-b.done:
+			for len(client) > 0 {
+				fmt.Fprintf(w, "event: result\ndata: %s\n\n", <-client)
+			}
+			fmt.Fprint(w, "event: done\ndata: {}\n\n")
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			hclog.Default().Debug("sse client disconnected", "remote", r.RemoteAddr)
+			return
+		}
+	}
+}
+
+func (c *Crawler) startSse() {
+	mux := http.NewServeMux()
+	mux.Handle("/events", c.sse)
+	c.sseServer = &http.Server{Addr: c.opts.SSEAddr, Handler: mux}
+
+	go func() {
+		hclog.Default().Info("serving results as server-sent events", "addr", c.opts.SSEAddr, "path", "/events")
+		if err := c.sseServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			hclog.Default().Error("sse server failed", "error", err)
+		}
+	}()
+}
+
+func (c *Crawler) stopSse() {
+	c.sse.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := c.sseServer.Shutdown(ctx); err != nil {
+		hclog.Default().Error("failed to shut down sse server", "error", err)
+	}
+}
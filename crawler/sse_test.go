@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSseBrokerStreamsResults(t *testing.T) {
+	broker := newSseBroker()
+	server := httptest.NewServer(broker)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected: %s, actual: %s", "text/event-stream", res.Header.Get("Content-Type"))
+	}
+
+	waitFor(t, func() bool { return broker.size() == 1 })
+	broker.publish(Result{URL: "https://monzo.com", Status: 200})
+	broker.close()
+
+	var lines []string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	output := strings.TrimSpace(strings.Join(lines, "\n"))
+	if !strings.Contains(output, "event: result\ndata: {\"url\":\"https://monzo.com\"") {
+		t.Fatalf("expected result event, actual: %s", output)
+	}
+
+	if !strings.HasSuffix(output, "event: done\ndata: {}") {
+		t.Fatalf("expected done event, actual: %s", output)
+	}
+}
+
+func TestSseBrokerHandlesDisconnect(t *testing.T) {
+	broker := newSseBroker()
+	server := httptest.NewServer(broker)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return broker.size() == 1 })
+	cancel()
+	res.Body.Close()
+
+	waitFor(t, func() bool { return broker.size() == 0 })
+	broker.publish(Result{URL: "https://monzo.com"})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second * 5)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"maps"
+	"time"
+)
+
+type Stats struct {
+	Requests            int
+	Pages               int
+	BytesDownloaded     int64
+	AverageResponseTime time.Duration
+	StatusCodes         map[int]int
+	PagesPerSecond      float64
+	Duration            time.Duration
+}
+
+func (c *Crawler) Stats() Stats {
+	c.resultLock.Lock()
+	statuses := maps.Clone(c.statuses)
+	var total time.Duration
+	for _, t := range c.timings {
+		total += t.Duration
+	}
+	timed := len(c.timings)
+	c.resultLock.Unlock()
+
+	stats := Stats{
+		Pages:           c.visited.Size(),
+		BytesDownloaded: c.parser.DownloadedBytes(),
+		StatusCodes:     statuses,
+	}
+
+	for _, count := range statuses {
+		stats.Requests += count
+	}
+
+	if timed > 0 {
+		stats.AverageResponseTime = total / time.Duration(timed)
+	}
+
+	if !c.started.IsZero() {
+		finished := c.finished
+		if finished.IsZero() {
+			finished = time.Now()
+		}
+		stats.Duration = finished.Sub(c.started)
+	}
+
+	if stats.Duration > 0 {
+		stats.PagesPerSecond = float64(stats.Pages) / stats.Duration.Seconds()
+	}
+	return stats
+}
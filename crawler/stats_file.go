@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"encoding/json"
+
+	"github.com/denis101/monzo-techtest/parser"
+	"github.com/hashicorp/go-hclog"
+)
+
+type statsSnapshot struct {
+	Visited int          `json:"visited"`
+	Queued  int          `json:"queued"`
+	Errors  int          `json:"errors"`
+	RPS     float64      `json:"rps"`
+	Elapsed float64      `json:"elapsed"`
+	Done    bool         `json:"done"`
+	Slowest []pageTiming `json:"slowest,omitempty"`
+	Largest []pageTiming `json:"largest,omitempty"`
+}
+
+func (c *Crawler) statsSnapshot(done bool) statsSnapshot {
+	current := c.Stats()
+	stats := statsSnapshot{
+		Visited: current.Pages,
+		Queued:  max(c.cache.Size()-current.Pages, 0),
+		Errors:  int(c.failures.Load()),
+		RPS:     current.PagesPerSecond,
+		Elapsed: current.Duration.Seconds(),
+		Done:    done,
+	}
+
+	if done {
+		stats.Slowest = c.SlowestPages(c.opts.TopN)
+		stats.Largest = c.LargestPages(c.opts.TopN)
+	}
+	return stats
+}
+
+func (c *Crawler) writeStats(done bool) {
+	b, err := json.Marshal(c.statsSnapshot(done))
+	if err == nil {
+		err = parser.WriteFileAtomic(c.opts.StatsFile, b)
+	}
+
+	if err != nil {
+		hclog.Default().Error("failed to write stats", "filename", c.opts.StatsFile, "error", err)
+		return
+	}
+
+	hclog.Default().Trace("wrote stats", "filename", c.opts.StatsFile)
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrawlWritesStatsFile(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/missing">missing</a>`,
+		"/a": `<a href="/">home</a>`,
+	})
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "stats.json")
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, StatsFile: filename, FailOn: getTestFailOn()})
+	c.Crawl(server.URL)
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats statsSnapshot
+	if err := json.Unmarshal(b, &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if !stats.Done || stats.Visited != 3 || stats.Queued != 0 {
+		t.Fatalf("expected visited: %d, actual visited: %d, queued: %d, done: %t", 3, stats.Visited, stats.Queued, stats.Done)
+	}
+
+	if stats.Errors != 1 {
+		t.Fatalf("expected errors: %d, actual errors: %d", 1, stats.Errors)
+	}
+
+	if stats.Elapsed <= 0 || stats.RPS <= 0 {
+		t.Fatalf("expected positive elapsed and rps, actual: %f %f", stats.Elapsed, stats.RPS)
+	}
+
+	current := c.Stats()
+	if stats.Elapsed != current.Duration.Seconds() || stats.RPS != current.PagesPerSecond || stats.Visited != current.Pages {
+		t.Fatalf("expected: %f %f %d, actual: %f %f %d", current.Duration.Seconds(), current.PagesPerSecond, current.Pages, stats.Elapsed, stats.RPS, stats.Visited)
+	}
+}
@@ -0,0 +1,34 @@
+package crawler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCrawlStats(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":  `<a href="/a">a</a><a href="/missing">missing</a>`,
+		"/a": `<p>about</p>`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2})
+	c.Crawl(server.URL)
+	stats := c.Stats()
+
+	if stats.Requests != 3 {
+		t.Fatalf("expected requests: %d, actual requests: %d", 3, stats.Requests)
+	}
+
+	if stats.StatusCodes[http.StatusOK] != 2 || stats.StatusCodes[http.StatusNotFound] != 1 {
+		t.Fatalf("expected: %d %d, actual: %d %d", 2, 1, stats.StatusCodes[http.StatusOK], stats.StatusCodes[http.StatusNotFound])
+	}
+
+	if stats.BytesDownloaded <= 0 {
+		t.Fatalf("expected bytes downloaded, actual: %d", stats.BytesDownloaded)
+	}
+
+	if stats.Duration <= 0 || stats.AverageResponseTime <= 0 || stats.PagesPerSecond <= 0 {
+		t.Fatalf("expected non-zero timings, actual: %+v", stats)
+	}
+}
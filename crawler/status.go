@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+func (r StatusRange) Contains(status int) bool {
+	return status >= r.Min && status <= r.Max
+}
+
+func ParseStatusRanges(values []string) ([]StatusRange, error) {
+	var ranges []StatusRange
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if len(v) <= 0 {
+			continue
+		}
+
+		if len(v) == 3 && strings.HasSuffix(v, "xx") {
+			class, err := strconv.Atoi(v[:1])
+			if err != nil || class < 1 || class > 5 {
+				return nil, fmt.Errorf("invalid status class %s", v)
+			}
+
+			ranges = append(ranges, StatusRange{Min: class * 100, Max: class*100 + 99})
+			continue
+		}
+
+		status, err := strconv.Atoi(v)
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid status code %s", v)
+		}
+
+		ranges = append(ranges, StatusRange{Min: status, Max: status})
+	}
+
+	return ranges, nil
+}
+
+func matchesStatus(ranges []StatusRange, status int) bool {
+	for _, r := range ranges {
+		if r.Contains(status) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAuthRequired(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
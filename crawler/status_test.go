@@ -0,0 +1,45 @@
+package crawler
+
+import "testing"
+
+func TestParseStatusRangesClasses(t *testing.T) {
+	ranges, err := ParseStatusRanges([]string{"4xx", "5XX"})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	for _, status := range []int{400, 404, 499, 500, 503, 599} {
+		if !matchesStatus(ranges, status) {
+			t.Fatalf("expected %d to match", status)
+		}
+	}
+
+	for _, status := range []int{200, 301, 399, 600} {
+		if matchesStatus(ranges, status) {
+			t.Fatalf("expected %d not to match", status)
+		}
+	}
+}
+
+func TestParseStatusRangesCodes(t *testing.T) {
+	ranges, err := ParseStatusRanges([]string{"404", " 410 "})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if !matchesStatus(ranges, 404) || !matchesStatus(ranges, 410) {
+		t.Fatal("expected 404 and 410 to match")
+	}
+
+	if matchesStatus(ranges, 403) {
+		t.Fatal("expected 403 not to match")
+	}
+}
+
+func TestParseStatusRangesInvalid(t *testing.T) {
+	for _, v := range []string{"abc", "9xx", "4x", "42", "700"} {
+		if _, err := ParseStatusRanges([]string{v}); err == nil {
+			t.Fatalf("expected error for %s", v)
+		}
+	}
+}
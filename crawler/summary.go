@@ -0,0 +1,210 @@
+package crawler
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pterm/pterm"
+)
+
+func (c *Crawler) Results() []Result {
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	results := make([]Result, len(c.result))
+	for i, r := range c.result {
+		r.Links = slices.Clone(r.Links)
+		r.ExternalLinks = slices.Clone(r.ExternalLinks)
+		r.Pagination = slices.Clone(r.Pagination)
+		results[i] = r
+	}
+	return results
+}
+
+func (c *Crawler) DepthDistribution() map[int]int {
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	distribution := make(map[int]int, len(c.depths))
+	for depth, count := range c.depths {
+		distribution[depth] = count
+	}
+	return distribution
+}
+
+func (c *Crawler) InDegree() map[string]int {
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	degrees := make(map[string]int, len(c.inDegree))
+	for url, degree := range c.inDegree {
+		degrees[url] = degree
+	}
+	return degrees
+}
+
+func (c *Crawler) OrphanPages() []string {
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	var orphans []string
+	for url := range c.recorded {
+		if url == c.seed || c.inDegree[url] > 0 {
+			continue
+		}
+		orphans = append(orphans, url)
+	}
+
+	sort.Strings(orphans)
+	return orphans
+}
+
+func (c *Crawler) countInbound(result Result) {
+	if _, ok := c.inDegree[result.URL]; !ok {
+		c.inDegree[result.URL] = 0
+	}
+
+	for _, l := range distinctStrings(result.Links) {
+		if l != result.URL {
+			c.inDegree[l]++
+		}
+	}
+}
+
+func (c *Crawler) settleDepths() {
+	c.depthLock.Lock()
+	defer c.depthLock.Unlock()
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	settled := make(map[string]int)
+	for url, depth := range c.recorded {
+		d, ok := c.discovered[url]
+		if !ok || d >= depth {
+			continue
+		}
+
+		c.depths[depth]--
+		if c.depths[depth] <= 0 {
+			delete(c.depths, depth)
+		}
+		c.depths[d]++
+		c.recorded[url] = d
+		settled[url] = d
+	}
+
+	for i, r := range c.result {
+		if d, ok := settled[r.URL]; ok && !r.Asset {
+			c.result[i].Depth = d
+		}
+	}
+
+	updater, ok := c.writer.(depthUpdater)
+	if !ok {
+		return
+	}
+
+	for url, d := range settled {
+		if err := updater.updateDepth(url, d); err != nil {
+			hclog.Default().Error("failed to update result depth", "input", url, "error", err)
+		}
+	}
+}
+
+type pageTiming struct {
+	URL      string        `json:"url"`
+	Duration time.Duration `json:"duration"`
+	Bytes    int64         `json:"bytes"`
+}
+
+func (c *Crawler) recordTiming(url string, duration time.Duration, bytes int64) {
+	c.resultLock.Lock()
+	defer c.resultLock.Unlock()
+
+	c.timings = append(c.timings, pageTiming{URL: url, Duration: duration, Bytes: bytes})
+}
+
+func (c *Crawler) SlowestPages(n int) []pageTiming {
+	return c.topPages(n, func(a, b pageTiming) bool { return a.Duration > b.Duration })
+}
+
+func (c *Crawler) LargestPages(n int) []pageTiming {
+	return c.topPages(n, func(a, b pageTiming) bool { return a.Bytes > b.Bytes })
+}
+
+func (c *Crawler) topPages(n int, less func(a, b pageTiming) bool) []pageTiming {
+	c.resultLock.Lock()
+	timings := make([]pageTiming, len(c.timings))
+	copy(timings, c.timings)
+	c.resultLock.Unlock()
+
+	sort.SliceStable(timings, func(i, j int) bool { return less(timings[i], timings[j]) })
+	if n < len(timings) {
+		timings = timings[:max(n, 0)]
+	}
+	return timings
+}
+
+func (c *Crawler) summary() {
+	distribution := c.DepthDistribution()
+	depths := make([]int, 0, len(distribution))
+	for depth := range distribution {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+
+	slowest := c.SlowestPages(c.opts.TopN)
+	largest := c.LargestPages(c.opts.TopN)
+
+	if !c.opts.Interactive {
+		hclog.Default().Info("crawl summary",
+			"pages", c.visited.Size(),
+			"failures", c.failures.Load(),
+			"depths", distribution,
+		)
+		for _, p := range slowest {
+			hclog.Default().Info("slow page", "url", p.URL, "duration", p.Duration)
+		}
+		for _, p := range largest {
+			hclog.Default().Info("large page", "url", p.URL, "bytes", p.Bytes)
+		}
+		return
+	}
+
+	bars := make(pterm.Bars, len(depths))
+	for i, depth := range depths {
+		bars[i] = pterm.Bar{
+			Label: fmt.Sprintf("depth %d", depth),
+			Value: distribution[depth],
+		}
+	}
+
+	pterm.DefaultSection.Println("Pages per depth")
+	if err := pterm.DefaultBarChart.WithHorizontal().WithShowValue().WithBars(bars).Render(); err != nil {
+		hclog.Default().Error("failed to render depth distribution", "error", err)
+	}
+
+	if len(slowest) > 0 {
+		pterm.DefaultSection.Println("Slowest pages")
+		c.renderTopPages(slowest, func(p pageTiming) string { return p.Duration.Round(time.Millisecond).String() })
+	}
+
+	if len(largest) > 0 {
+		pterm.DefaultSection.Println("Largest pages")
+		c.renderTopPages(largest, func(p pageTiming) string { return fmt.Sprintf("%d bytes", p.Bytes) })
+	}
+}
+
+func (c *Crawler) renderTopPages(pages []pageTiming, value func(pageTiming) string) {
+	data := pterm.TableData{{"URL", "Value"}}
+	for _, p := range pages {
+		data = append(data, []string{p.URL, value(p)})
+	}
+
+	if err := pterm.DefaultTable.WithHasHeader().WithData(data).Render(); err != nil {
+		hclog.Default().Error("failed to render top pages", "error", err)
+	}
+}
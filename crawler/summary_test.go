@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDepthDistribution(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":       `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a":      `<a href="/a/deep">deep</a>`,
+		"/b":      `<a href="/">home</a>`,
+		"/a/deep": `deep`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.Crawl(server.URL)
+
+	distribution := c.DepthDistribution()
+	expected := map[int]int{0: 1, 1: 2, 2: 1}
+	if len(distribution) != len(expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, distribution)
+	}
+
+	for depth, count := range expected {
+		if distribution[depth] != count {
+			t.Fatalf("expected: %v, actual: %v", expected, distribution)
+		}
+	}
+}
+
+func TestMinDepthKeepsShallowest(t *testing.T) {
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+
+	for _, step := range []struct{ depth, expected int }{{3, 3}, {1, 1}, {2, 1}, {0, 0}} {
+		if actual := c.minDepth(crawlTask{url: "https://monzo.com/a", depth: step.depth}); actual != step.expected {
+			t.Fatalf("expected: %d, actual: %d", step.expected, actual)
+		}
+	}
+}
+
+func TestCrawlResultDepths(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":       `<a href="/a">a</a><a href="/b">b</a>`,
+		"/a":      `<a href="/a/deep">deep</a><a href="/b">b</a>`,
+		"/b":      `<a href="/">home</a>`,
+		"/a/deep": `deep`,
+	})
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.Crawl(server.URL)
+
+	expected := map[string]int{server.URL: 0, server.URL + "/a": 1, server.URL + "/b": 1, server.URL + "/a/deep": 2}
+	if len(c.result) != len(expected) {
+		t.Fatalf("expected len: %d, actual len: %d", len(expected), len(c.result))
+	}
+
+	for _, r := range c.result {
+		if r.Depth != expected[r.URL] {
+			t.Fatalf("expected: %d, actual: %d for %s", expected[r.URL], r.Depth, r.URL)
+		}
+	}
+}
+
+func TestCrawlResultDepthsKeepShallowest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/slow">slow</a><a href="/fast">fast</a>`)
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+			fmt.Fprint(w, `<a href="/target">target</a>`)
+		case "/fast":
+			fmt.Fprint(w, `<a href="/middle">middle</a>`)
+		case "/middle":
+			fmt.Fprint(w, `<a href="/target">target</a>`)
+		}
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 2})
+	c.Crawl(server.URL)
+
+	for _, r := range c.result {
+		if r.URL == server.URL+"/target" && r.Depth != 2 {
+			t.Fatalf("expected: %d, actual: %d", 2, r.Depth)
+		}
+	}
+
+	if distribution := c.DepthDistribution(); distribution[2] != 2 || distribution[3] != 0 {
+		t.Fatalf("expected: %v, actual: %v", map[int]int{0: 1, 1: 2, 2: 2}, distribution)
+	}
+}
+
+func TestInDegree(t *testing.T) {
+	server := newTestSite(map[string]string{
+		"/":    `<a href="/a">a</a><a href="/b">b</a><a href="/hub">hub</a>`,
+		"/a":   `<a href="/hub">hub</a><a href="/hub">hub again</a><a href="/a">self</a>`,
+		"/b":   `<a href="/hub">hub</a><a href="/">home</a>`,
+		"/hub": `<a href="/a">a</a>`,
+	})
+	defer server.Close()
+
+	expected := map[string]int{server.URL: 1, server.URL + "/a": 2, server.URL + "/b": 1, server.URL + "/hub": 3}
+	for _, format := range []CrawlerOutputFormat{Output_Json, Output_Jsonl} {
+		c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, OutputFormat: format, OutputWriter: &bytes.Buffer{}})
+		c.Crawl(server.URL)
+
+		degrees := c.InDegree()
+		if len(degrees) != len(expected) {
+			t.Fatalf("expected: %v, actual: %v for %s", expected, degrees, format)
+		}
+
+		for url, d := range expected {
+			if degrees[url] != d {
+				t.Fatalf("expected: %d, actual: %d for %s with %s", d, degrees[url], url, format)
+			}
+		}
+	}
+}
+
+func TestOrphanPages(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<urlset xmlns="%[1]s"><url><loc>%[2]s/a</loc></url><url><loc>%[2]s/orphan</loc></url></urlset>`, SitemapNamespace, server.URL)
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/">home</a>`)
+		case "/orphan":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/orphan">self</a>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, format := range []CrawlerOutputFormat{Output_Json, Output_Jsonl} {
+		c := getTestCrawler(CrawlerOptions{MaxWorkers: 1, SeedFromSitemap: true, OutputFormat: format, OutputWriter: &bytes.Buffer{}})
+		c.Crawl(server.URL)
+
+		orphans := c.OrphanPages()
+		if len(orphans) != 1 || orphans[0] != server.URL+"/orphan" {
+			t.Fatalf("expected: %v, actual: %v for %s", []string{server.URL + "/orphan"}, orphans, format)
+		}
+	}
+}
+
+func TestSlowestAndLargestPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/large":
+			fmt.Fprint(w, strings.Repeat("x", 1000))
+		default:
+			fmt.Fprint(w, `<a href="/slow">slow</a><a href="/large">large</a>`)
+		}
+	}))
+	defer server.Close()
+
+	c := getTestCrawler(CrawlerOptions{MaxWorkers: 1})
+	c.Crawl(server.URL)
+
+	slowest := c.SlowestPages(1)
+	if len(slowest) != 1 || slowest[0].URL != server.URL+"/slow" {
+		t.Fatalf("expected: %s, actual: %v", server.URL+"/slow", slowest)
+	}
+
+	largest := c.LargestPages(2)
+	if len(largest) != 2 || largest[0].URL != server.URL+"/large" || largest[0].Bytes != 1000 {
+		t.Fatalf("expected: %s, actual: %v", server.URL+"/large", largest)
+	}
+
+	if len(c.SlowestPages(0)) != 0 {
+		t.Fatalf("expected len: %d, actual len: %d", 0, len(c.SlowestPages(0)))
+	}
+}
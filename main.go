@@ -3,9 +3,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/denis101/monzo-techtest/crawler"
+	"github.com/denis101/monzo-techtest/parser"
+	"github.com/denis101/monzo-techtest/scheduler"
 	hclog "github.com/hashicorp/go-hclog"
 )
 
@@ -14,14 +21,112 @@ var traceLogFlag = flag.Bool("vv", false, "Enable TRACE level logging")
 var logJsonFlag = flag.Bool("json-log", false, "Enable json logging")
 
 var urlFlag = flag.String("url", "https://crawler-test.com/", "URL to crawl")
+var checkpointFlag = flag.String("checkpoint", "", "Periodically write crawl progress to the provided file")
+var checkpointIntervalFlag = flag.Duration("checkpoint-interval", crawler.DefaultCheckpointInterval, "Interval between checkpoint writes")
+var statsFileFlag = flag.String("stats-file", "", "Periodically write a JSON snapshot of crawl statistics to the provided file")
+var statsIntervalFlag = flag.Duration("stats-interval", crawler.DefaultStatsInterval, "Interval between stats file writes")
+var resumeFlag = flag.String("resume", "", "Resume a crawl from the provided checkpoint file")
+var sseFlag = flag.String("sse", "", "Serve results as server-sent events on the provided address (e.g. :8080)")
+var debugUrlFlag = flag.String("debug-url", "", "Fetch a single URL and print its request/response metadata and link filtering decisions")
 var outputFlag = flag.String("o", "", "Output filename")
-var formatFlag = flag.String("f", "stdout", "Output format [stdout|json|xml]")
+var formatFlag = flag.String("f", "stdout", "Output format [stdout|json|xml|sqlite|parquet|edges|dot|sitemap|csv|jsonl]")
+var edgeDelimiterFlag = flag.String("edge-delim", crawler.DefaultEdgeDelimiter, "Delimiter between source and target URLs in the edges format")
 var interactiveFlag = flag.Bool("i", false, "Interactive mode")
 var maxWorkersFlag = flag.Int("workers", 2, "Amount of worker threads")
+var autoTuneFlag = flag.Bool("autotune", false, "Adjust the amount of active workers based on observed request latency and errors, up to -workers")
+var minWorkersFlag = flag.Int("min-workers", 1, "Minimum amount of active workers when auto-tuning")
+var targetLatencyFlag = flag.Duration("target-latency", time.Millisecond*500, "Target request latency when auto-tuning")
+var tuneWindowFlag = flag.Int("tune-window", scheduler.DefaultTuneWindow, "Amount of requests averaged before each auto-tuning adjustment")
+var maxInFlightFlag = flag.Int("max-inflight", 0, "Maximum amount of simultaneous HTTP requests across all workers, 0 for unlimited")
+var acceptFlag = flag.String("accept", parser.DefaultAccept, "Accept header sent with each request")
+var userAgentFlag = flag.String("ua", parser.DefaultUserAgent, "User-Agent header sent with each request")
+var cookieFileFlag = flag.String("cookies", "", "Netscape format cookies.txt file to pre-populate the cookie jar from")
+var maxDepthFlag = flag.Int("max-depth", 0, "Only follow links up to this many hops from the seed, 0 for unlimited")
+var breadthFirstFlag = flag.Bool("breadth-first", false, "Crawl pages closer to the seed before deeper ones")
+var maxPagesFlag = flag.Int("max-pages", 0, "Stop the crawl gracefully once N pages have been fetched, 0 for unlimited")
+var maxDurationFlag = flag.Duration("max-duration", 0, "Stop the crawl gracefully after this long (e.g. 30s), 0 for unlimited")
+var radiusFlag = flag.Int("radius", 0, "Only crawl pages within this many undirected link-hops of the seed, 0 for unlimited")
 var deadlineFlag = flag.Int("deadline", 5, "HTTP request deadline in seconds")
+var maxIdleConnsFlag = flag.Int("max-idle-conns-per-host", 0, "Idle connections kept open per host for reuse, 0 to match -workers")
+var maxConnsFlag = flag.Int("max-conns-per-host", 0, "Limit the connections open to each host, 0 for no limit")
+var idleConnTimeoutFlag = flag.Duration("idle-conn-timeout", 0, "Close idle connections after this long, 0 for the default")
+var disableKeepAlivesFlag = flag.Bool("disable-keep-alives", false, "Open a new connection for every request")
+var dialTimeoutFlag = flag.Duration("dial-timeout", 0, "Timeout for establishing each connection, 0 for the default")
+var tlsTimeoutFlag = flag.Duration("tls-timeout", 0, "Timeout for each TLS handshake, 0 for the default")
+var headerTimeoutFlag = flag.Duration("header-timeout", 0, "Timeout waiting for response headers once a request is sent, 0 for no limit other than -deadline")
 var ignoreFragmentsFlag = flag.Bool("fragments", true, "Ignore URLs with fragments in their paths")
 var ignoredExtensionsFlag = flag.String("ext", "", "Ignore URLs ending in the provided extensions (e.g. .jpg)")
 var ignoredPathsFlag = flag.String("paths", "", "Ignore URLs containing the provided strings in their paths")
+var denyPathsFlag = flag.String("deny-paths", "", "Ignore URLs whose path is, or is beneath, any of the provided prefixes (e.g. /admin)")
+var denyHostsFlag = flag.String("deny-hosts", "", "Ignore URLs on any of the provided hosts and their subdomains")
+var includeFlag = flag.String("include", "", "Only follow URLs matching the provided regular expression (e.g. ^https://monzo\\.com/blog/)")
+var excludeFlag = flag.String("exclude", "", "Ignore URLs matching the provided regular expression (e.g. \\.pdf$)")
+var httpCacheDirFlag = flag.String("http-cache", "", "Directory used to cache responses, honouring Cache-Control and Expires headers and revalidating stale responses with ETag or Last-Modified")
+var softErrorMarkersFlag = flag.String("soft-errors", "", "Flag pages whose body contains any of the provided strings as soft errors (e.g. Page Not Found)")
+var skipSoftErrorLinksFlag = flag.Bool("skip-soft-error-links", false, "Don't follow links found on pages flagged as soft errors")
+var inlineJsFlag = flag.Bool("inline-js", false, "Best-effort extraction of URLs from inline onclick/onmousedown handlers")
+var recordErrorsFlag = flag.Bool("record-errors", false, "Include pages that failed to fetch or parse in the output, with their error")
+var summaryFlag = flag.Bool("summary", false, "Report pages, failures, the page depth distribution and the slowest and largest pages when the crawl finishes")
+var topFlag = flag.Int("top", crawler.DefaultTopN, "Number of slowest and largest pages listed by -summary and -stats-file")
+var failFastFlag = flag.Bool("fail-fast", false, "Stop the crawl and exit non-zero on the first failed page")
+var failOnFlag = flag.String("fail-on", strings.Join(crawler.DefaultFailOn, ","), "HTTP statuses treated as failures, as classes or codes (e.g. 4xx,5xx,404)")
+var dedupContentFlag = flag.Bool("dedup-content", false, "Flag pages with byte-identical content as duplicates and don't follow their links")
+var hostAliasesFlag = flag.String("host-alias", "", "Treat hosts as aliases of a canonical host for dedup (e.g. m.monzo.com=monzo.com)")
+var rateFlag = flag.Float64("rate", 0, "Maximum requests per second sent to each host, 0 for no limit")
+var retriesFlag = flag.Int("retries", 0, "Retry requests that fail to connect or return 429, 502, 503 or 504 up to N times")
+var retryBackoffFlag = flag.Duration("retry-backoff", parser.DefaultRetryBackoff, "Delay before the first retry, doubled for each further attempt, a 429 Retry-After header pauses the host instead")
+var maxRedirectsFlag = flag.Int("max-redirects", parser.DefaultMaxRedirects, "Maximum number of redirects followed for each request")
+var normalizeQueryFlag = flag.Bool("normalize-query", false, "Treat URLs with different query strings as different pages, sorting their parameters so the order doesn't matter")
+var stripParamsFlag = flag.String("strip-params", strings.Join(parser.DefaultStripQueryParams, ","), "Query parameters removed when -normalize-query is set, a trailing * matches a prefix")
+var contentTypesFlag = flag.String("content-types", strings.Join(parser.DefaultParseableContentTypes, ","), "Only extract links from responses with one of these content types")
+var domainsFlag = flag.String("domains", "", "Follow links to any of the provided domains and their subdomains instead of only the seed's host (e.g. monzo.com)")
+var nofollowFlag = flag.Bool("nofollow", false, "Don't follow links marked rel=nofollow")
+var paginationFlag = flag.Bool("pagination", false, "Follow rel=prev/next pagination link tags")
+var metaRefreshFlag = flag.Bool("meta-refresh", false, "Follow <meta http-equiv=\"refresh\"> redirect targets")
+var canonicalFlag = flag.Bool("canonical", false, "Dedupe pages against their <link rel=\"canonical\"> URL, attributing their links to it")
+var sitemapFlag = flag.Bool("sitemap", false, "Seed the crawl with every page listed in <url>/sitemap.xml, following sitemap indexes")
+var maxOutputFlag = flag.Int("max-output", 0, "Only output the first N results in crawl order, 0 for all")
+var scopeGlobsFlag = flag.String("scope", "", "Only follow URLs matching any of the provided globs, * within a path segment and ** across segments (e.g. https://monzo.com/blog/**)")
+var referrerFlag = flag.Bool("referrer", false, "Record the first page that linked to each result")
+var externalFlag = flag.Bool("external", false, "Record off-domain links on each result without crawling them")
+var checkExternalFlag = flag.Bool("check-external", false, "Check each distinct off-domain link with a HEAD request and report its status, implies -external")
+var maxBodyBytesFlag = flag.Int64("max-body-bytes", 0, "Only parse the first N bytes of each response body, 0 for no limit")
+var probeHeadFlag = flag.Bool("probe-head", false, "Send a HEAD request before each GET and skip pages that are not parseable or larger than -max-body-bytes")
+var maxTotalBytesFlag = flag.Int64("max-bytes", 0, "Stop the crawl gracefully once more than N response body bytes have been downloaded (cache hits are free), 0 for no limit")
+var checkAssetsFlag = flag.Bool("check-assets", false, "Status check linked assets (images, scripts, stylesheets and links with ignored extensions) with HEAD requests")
+var requireContentLengthFlag = flag.Bool("require-content-length", false, "Treat assets without a Content-Length as errors when checking assets")
+var measureUnknownBodiesFlag = flag.Bool("measure-unknown-bodies", false, "Download assets without a Content-Length to measure their size when checking assets")
+var parseCSSUrlsFlag = flag.Bool("parse-css-urls", false, "Also check url() references in inline <style> blocks and style attributes when checking assets")
+var checkInternalLinksFlag = flag.Bool("check-internal-links", false, "Crawl the whole site and report broken internal links with the pages that reference them instead of the results, exiting non-zero if any are broken")
+var authRequiredFlag = flag.Bool("auth-required", false, "Classify 401 and 403 responses as auth-required instead of failures or broken links")
+var plateauFlag = flag.Float64("plateau", 0, "Stop the crawl once the ratio of newly discovered to total links over the plateau window drops below this threshold (e.g. 0.05), 0 to disable")
+var plateauWindowFlag = flag.Int("plateau-window", crawler.DefaultPlateauWindow, "Number of recent pages considered when detecting a plateau")
+var headOnlyFlag = flag.Bool("head-only", false, "Stop parsing each page at the end of its <head>, only following canonical, alternate, prev, next and stylesheet <link> relationships")
+var basicAuthUserFlag = flag.String("user", "", "Username for HTTP basic authentication")
+var basicAuthPassFlag = flag.String("pass", "", "Password for HTTP basic authentication")
+var basicAuthHostsFlag = flag.String("auth-hosts", "", "Only send basic authentication credentials to these hosts, defaults to the host of -url")
+var insecureFlag = flag.Bool("insecure", false, "Skip TLS certificate verification, for internal sites with self-signed certificates")
+var proxyFlag = flag.String("proxy", "", "Route requests through the provided http, https or socks5 proxy (e.g. socks5://localhost:1080)")
+var headersFlag stringList
+var hostHeadersFlag stringList
+var canonicalSchemeFlag = flag.Bool("canonical-scheme", false, "Treat http and https links to the same host as duplicates, preferring https")
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func init() {
+	flag.Var(&headersFlag, "header", "Header sent with each request, may be repeated (e.g. \"Authorization: Bearer token\")")
+	flag.Var(&headersFlag, "H", "Shorthand for -header")
+	flag.Var(&hostHeadersFlag, "host-header", "Header sent with requests to a single host, overriding -header, may be repeated (e.g. \"api.monzo.com=Authorization: Bearer token\")")
+}
 
 func main() {
 	flag.Parse()
@@ -42,28 +147,233 @@ func main() {
 		panic(fmt.Errorf("client error: invalid parameter url, missing scheme in [%s]", *urlFlag))
 	}
 
-	if *formatFlag != string(crawler.Output_Stdout) && *formatFlag != string(crawler.Output_Json) && *formatFlag != string(crawler.Output_Xml) {
+	if !slices.Contains(crawler.OutputFormats, crawler.CrawlerOutputFormat(*formatFlag)) {
 		panic(fmt.Errorf("client error: invalid parameter o, unsupported format [%s]", *formatFlag))
 	}
 
+	if (*formatFlag == string(crawler.Output_Sqlite) || *formatFlag == string(crawler.Output_Parquet)) && len(*outputFlag) <= 0 {
+		panic(fmt.Errorf("client error: invalid parameter o, format [%s] requires an output filename", *formatFlag))
+	}
+
 	var ignoredExtensions []string
 	if len(*ignoredExtensionsFlag) > 0 {
 		ignoredExtensions = strings.Split(*ignoredExtensionsFlag, ",")
 	}
 
 	var ignoredPaths []string
-	if len(*ignoredExtensionsFlag) > 0 {
+	if len(*ignoredPathsFlag) > 0 {
 		ignoredPaths = strings.Split(*ignoredPathsFlag, ",")
 	}
 
-	crawler.NewCrawler(crawler.CrawlerOptions{
-		MaxWorkers:        *maxWorkersFlag,
-		OutputFormat:      crawler.CrawlerOutputFormat(*formatFlag),
-		OutputFile:        *outputFlag,
-		Interactive:       *interactiveFlag,
-		RequestDeadline:   *deadlineFlag,
-		IgnoreFragments:   *ignoreFragmentsFlag,
-		IgnoredExtensions: ignoredExtensions,
-		IgnoredPaths:      ignoredPaths,
-	}).Crawl(*urlFlag)
+	var denyPaths []string
+	if len(*denyPathsFlag) > 0 {
+		denyPaths = strings.Split(*denyPathsFlag, ",")
+	}
+
+	var denyHosts []string
+	if len(*denyHostsFlag) > 0 {
+		denyHosts = strings.Split(*denyHostsFlag, ",")
+	}
+
+	var softErrorMarkers []string
+	if len(*softErrorMarkersFlag) > 0 {
+		softErrorMarkers = strings.Split(*softErrorMarkersFlag, ",")
+	}
+
+	var scopeGlobs []string
+	if len(*scopeGlobsFlag) > 0 {
+		scopeGlobs = strings.Split(*scopeGlobsFlag, ",")
+	}
+
+	failOn, err := crawler.ParseStatusRanges(strings.Split(*failOnFlag, ","))
+	if err != nil {
+		panic(fmt.Errorf("client error: invalid parameter fail-on, %w", err))
+	}
+
+	if _, err := regexp.Compile(*includeFlag); err != nil {
+		panic(fmt.Errorf("client error: invalid parameter include, %w", err))
+	}
+
+	if _, err := regexp.Compile(*excludeFlag); err != nil {
+		panic(fmt.Errorf("client error: invalid parameter exclude, %w", err))
+	}
+
+	var allowedDomains []string
+	if len(*domainsFlag) > 0 {
+		allowedDomains = strings.Split(*domainsFlag, ",")
+	}
+
+	var contentTypes []string
+	if len(*contentTypesFlag) > 0 {
+		contentTypes = strings.Split(*contentTypesFlag, ",")
+	}
+
+	var stripParams []string
+	if len(*stripParamsFlag) > 0 {
+		stripParams = strings.Split(*stripParamsFlag, ",")
+	}
+
+	hostAliases := make(map[string]string)
+	if len(*hostAliasesFlag) > 0 {
+		for _, pair := range strings.Split(*hostAliasesFlag, ",") {
+			alias, canonical, ok := strings.Cut(pair, "=")
+			if !ok || len(alias) <= 0 || len(canonical) <= 0 {
+				panic(fmt.Errorf("client error: invalid parameter host-alias, expected alias=canonical in [%s]", pair))
+			}
+			hostAliases[strings.ToLower(alias)] = strings.ToLower(canonical)
+		}
+	}
+
+	headers := make(map[string]string)
+	for _, header := range headersFlag {
+		name, value, ok := parseHeader(header)
+		if !ok {
+			panic(fmt.Errorf("client error: invalid parameter header, expected name: value in [%s]", header))
+		}
+		headers[name] = value
+	}
+
+	headersPerHost := make(map[string]map[string]string)
+	for _, hostHeader := range hostHeadersFlag {
+		host, header, _ := strings.Cut(hostHeader, "=")
+		name, value, ok := parseHeader(header)
+		if len(host) <= 0 || !ok {
+			panic(fmt.Errorf("client error: invalid parameter host-header, expected host=name: value in [%s]", hostHeader))
+		}
+
+		host = strings.ToLower(host)
+		if headersPerHost[host] == nil {
+			headersPerHost[host] = make(map[string]string)
+		}
+		headersPerHost[host][name] = value
+	}
+
+	var basicAuthHosts []string
+	if len(*basicAuthHostsFlag) > 0 {
+		basicAuthHosts = strings.Split(*basicAuthHostsFlag, ",")
+	} else if seed, err := url.Parse(*urlFlag); err == nil {
+		basicAuthHosts = []string{seed.Host}
+	}
+
+	opts := crawler.CrawlerOptions{
+		MaxWorkers:           *maxWorkersFlag,
+		OutputFormat:         crawler.CrawlerOutputFormat(*formatFlag),
+		OutputFile:           *outputFlag,
+		Interactive:          *interactiveFlag,
+		HandleSignals:        true,
+		RequestDeadline:      *deadlineFlag,
+		DialTimeout:          *dialTimeoutFlag,
+		TLSHandshakeTimeout:  *tlsTimeoutFlag,
+		HeaderTimeout:        *headerTimeoutFlag,
+		MaxIdleConnsPerHost:  *maxIdleConnsFlag,
+		MaxConnsPerHost:      *maxConnsFlag,
+		IdleConnTimeout:      *idleConnTimeoutFlag,
+		DisableKeepAlives:    *disableKeepAlivesFlag,
+		IgnoreFragments:      *ignoreFragmentsFlag,
+		IgnoredExtensions:    ignoredExtensions,
+		IgnoredPaths:         ignoredPaths,
+		DenyPathPrefixes:     denyPaths,
+		DenyHosts:            denyHosts,
+		IncludeRegex:         *includeFlag,
+		ExcludeRegex:         *excludeFlag,
+		CanonicalizeScheme:   *canonicalSchemeFlag,
+		AutoTune:             *autoTuneFlag,
+		MinWorkers:           *minWorkersFlag,
+		TargetLatency:        *targetLatencyFlag,
+		TuneWindow:           *tuneWindowFlag,
+		HTTPCacheDir:         *httpCacheDirFlag,
+		SoftErrorMarkers:     softErrorMarkers,
+		SkipSoftErrorLinks:   *skipSoftErrorLinksFlag,
+		ParseInlineJSLinks:   *inlineJsFlag,
+		FailFast:             *failFastFlag,
+		RecordErrors:         *recordErrorsFlag,
+		Summary:              *summaryFlag,
+		TopN:                 *topFlag,
+		FailOn:               failOn,
+		MaxInFlightRequests:  *maxInFlightFlag,
+		DedupByContent:       *dedupContentFlag,
+		CheckpointFile:       *checkpointFlag,
+		CheckpointInterval:   *checkpointIntervalFlag,
+		ClassifyAuthRequired: *authRequiredFlag,
+		PlateauThreshold:     *plateauFlag,
+		PlateauWindow:        *plateauWindowFlag,
+		StatsFile:            *statsFileFlag,
+		StatsInterval:        *statsIntervalFlag,
+		ResumeFile:           *resumeFlag,
+		EdgeDelimiter:        *edgeDelimiterFlag,
+		Accept:               *acceptFlag,
+		UserAgent:            *userAgentFlag,
+		RespectNofollow:      *nofollowFlag,
+		RequestsPerSecond:    *rateFlag,
+		MaxRetries:           *retriesFlag,
+		RetryBackoff:         *retryBackoffFlag,
+		MaxRedirects:         *maxRedirectsFlag,
+		NormalizeQuery:       *normalizeQueryFlag,
+		AllowedDomains:       allowedDomains,
+		ContentTypes:         contentTypes,
+		StripQueryParams:     stripParams,
+		CookieFile:           *cookieFileFlag,
+		Radius:               *radiusFlag,
+		MaxDepth:             *maxDepthFlag,
+		BreadthFirst:         *breadthFirstFlag,
+		MaxPages:             *maxPagesFlag,
+		MaxDuration:          *maxDurationFlag,
+		SSEAddr:              *sseFlag,
+		HostAliases:          hostAliases,
+		FollowPagination:     *paginationFlag,
+		FollowMetaRefresh:    *metaRefreshFlag,
+		UseCanonical:         *canonicalFlag,
+		SeedFromSitemap:      *sitemapFlag,
+		MaxOutput:            *maxOutputFlag,
+		ScopeGlobs:           scopeGlobs,
+		RecordReferrer:       *referrerFlag,
+		RecordExternal:       *externalFlag,
+		CheckExternal:        *checkExternalFlag,
+		MaxTotalBytes:        *maxTotalBytesFlag,
+		MaxBodyBytes:         *maxBodyBytesFlag,
+		ProbeWithHead:        *probeHeadFlag,
+		HeadOnly:             *headOnlyFlag,
+		CheckAssets:          *checkAssetsFlag,
+		RequireContentLength: *requireContentLengthFlag,
+		MeasureUnknownBodies: *measureUnknownBodiesFlag,
+		ParseCSSUrls:         *parseCSSUrlsFlag,
+		CheckInternalLinks:   *checkInternalLinksFlag,
+		Headers:              headers,
+		HeadersPerHost:       headersPerHost,
+		BasicAuthUser:        *basicAuthUserFlag,
+		BasicAuthPass:        *basicAuthPassFlag,
+		BasicAuthHosts:       basicAuthHosts,
+		InsecureSkipVerify:   *insecureFlag,
+		ProxyURL:             *proxyFlag,
+	}
+
+	if len(*debugUrlFlag) > 0 {
+		diagnostic, err := crawler.Diagnose(opts, *debugUrlFlag)
+		if len(diagnostic.Status) > 0 {
+			fmt.Print(diagnostic)
+		}
+
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	c, err := crawler.NewCrawler(opts)
+	if err != nil {
+		panic(err)
+	}
+
+	outcome, err := c.Crawl(*urlFlag)
+	if err != nil {
+		hclog.Default().Error("crawl failed", "error", err)
+		os.Exit(1)
+	}
+	os.Exit(outcome.ExitCode())
+}
+
+func parseHeader(header string) (string, string, bool) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	return name, strings.TrimSpace(value), ok && len(name) > 0
 }
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+func loadCookieFile(filename string) (http.CookieJar, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	count := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+
+		if len(strings.TrimSpace(line)) <= 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cookieUrl, cookie, err := parseCookieLine(line)
+		if err != nil {
+			hclog.Default().Warn("skipping malformed cookie line", "filename", filename, "line", lineNumber, "error", err)
+			continue
+		}
+
+		cookie.HttpOnly = httpOnly
+		jar.SetCookies(cookieUrl, []*http.Cookie{cookie})
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	hclog.Default().Debug("loaded cookie file", "filename", filename, "cookies", count)
+	return jar, nil
+}
+
+func parseCookieLine(line string) (*url.URL, *http.Cookie, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 7 {
+		return nil, nil, fmt.Errorf("expected 7 tab separated fields, got %d", len(fields))
+	}
+
+	domain, includeSubdomains, path, secure, expiry, name, value :=
+		fields[0], fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]
+
+	if len(domain) <= 0 || len(name) <= 0 {
+		return nil, nil, fmt.Errorf("missing domain or name")
+	}
+
+	if !isCookieBool(includeSubdomains) || !isCookieBool(secure) {
+		return nil, nil, fmt.Errorf("invalid boolean field")
+	}
+
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid expiry %s", expiry)
+	}
+
+	scheme := "http"
+	if strings.EqualFold(secure, "TRUE") {
+		scheme = "https"
+	}
+
+	host := strings.TrimPrefix(domain, ".")
+	cookie := &http.Cookie{
+		Name:   name,
+		Value:  value,
+		Path:   path,
+		Secure: scheme == "https",
+	}
+
+	if strings.EqualFold(includeSubdomains, "TRUE") {
+		cookie.Domain = host
+	}
+
+	if expires > 0 {
+		cookie.Expires = time.Unix(expires, 0)
+	}
+
+	return &url.URL{Scheme: scheme, Host: host, Path: path}, cookie, nil
+}
+
+func isCookieBool(v string) bool {
+	return strings.EqualFold(v, "TRUE") || strings.EqualFold(v, "FALSE")
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCookieFile(t *testing.T) {
+	var session, missing string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			session = c.Value
+		}
+		if c, err := r.Cookie("malformed"); err == nil {
+			missing = c.Value
+		}
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "cookies.txt")
+	contents := "# Netscape HTTP Cookie File\n" +
+		"\n" +
+		fmt.Sprintf("#HttpOnly_127.0.0.1\tFALSE\t/\tFALSE\t%d\tsession\tabc123\n", time.Now().Add(time.Hour).Unix()) +
+		"127.0.0.1\tFALSE\t/\tnot-a-bool\t0\tmalformed\tvalue\n" +
+		"too\tfew\tfields\n"
+	if err := os.WriteFile(filename, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(ParserOptions{Timeout: time.Second, CookieFile: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if session != "abc123" {
+		t.Fatalf("expected: %s, actual: %s", "abc123", session)
+	}
+
+	if missing != "" {
+		t.Fatalf("expected malformed cookie to be skipped, actual: %s", missing)
+	}
+}
+
+func TestLoadCookieFileMissing(t *testing.T) {
+	_, err := NewParser(ParserOptions{CookieFile: filepath.Join(t.TempDir(), "missing.txt")})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseCookieLineSecureSubdomains(t *testing.T) {
+	u, cookie, err := parseCookieLine(".monzo.com\tTRUE\t/\tTRUE\t0\ttoken\txyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "https" || u.Host != "monzo.com" {
+		t.Fatalf("unexpected url: %s", u)
+	}
+
+	if cookie.Domain != "monzo.com" || !cookie.Secure || !cookie.Expires.IsZero() {
+		t.Fatalf("unexpected cookie: %+v", cookie)
+	}
+}
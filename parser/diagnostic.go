@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type Diagnostic struct {
+	URL           string
+	FinalURL      string
+	RedirectChain []string
+	Status        string
+	StatusCode    int
+	ContentType   string
+	Header        http.Header
+	BodySize      int64
+	Links         []LinkDiagnostic
+}
+
+type LinkDiagnostic struct {
+	Href   string
+	URL    string
+	Reason string
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.count += int64(n)
+	return n, err
+}
+
+func (p *Parser) Diagnose(input string) (Diagnostic, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	url, baseUrl, err := getUrl(input)
+	if err != nil {
+		return Diagnostic{}, err
+	}
+
+	response, err := p.get(ctx, *url)
+	if err != nil {
+		return Diagnostic{URL: input}, err
+	}
+
+	defer closeBody(response.Body)
+
+	body := &countingReader{reader: response.Body}
+	doc, err := parseLinksFromHtmlBody(body, p.opts)
+
+	diagnostic := Diagnostic{
+		URL:           input,
+		FinalURL:      response.URL,
+		RedirectChain: response.RedirectChain,
+		Status:        response.Status,
+		StatusCode:    response.StatusCode,
+		ContentType:   response.Header.Get("Content-Type"),
+		Header:        response.Header,
+		BodySize:      body.count,
+	}
+
+	if err != nil {
+		return diagnostic, err
+	}
+
+	if len(response.URL) > 0 {
+		baseUrl = response.URL
+	}
+
+	seen := make(map[string]bool)
+	for _, href := range doc.links {
+		link, reason := p.filterLink(href, baseUrl)
+		if len(reason) <= 0 && p.opts.Distinct && seen[link] {
+			reason = "duplicate"
+		}
+
+		if len(reason) <= 0 {
+			seen[link] = true
+		}
+
+		diagnostic.Links = append(diagnostic.Links, LinkDiagnostic{
+			Href:   href,
+			URL:    link,
+			Reason: reason,
+		})
+	}
+
+	return diagnostic, nil
+}
+
+func (d Diagnostic) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "URL:            %s\n", d.URL)
+	fmt.Fprintf(&builder, "Final URL:      %s\n", d.FinalURL)
+	fmt.Fprintf(&builder, "Status:         %s\n", d.Status)
+	fmt.Fprintf(&builder, "Content-Type:   %s\n", d.ContentType)
+	fmt.Fprintf(&builder, "Body size:      %d bytes\n", d.BodySize)
+
+	fmt.Fprintf(&builder, "Redirect chain:\n")
+	for _, r := range d.RedirectChain {
+		fmt.Fprintf(&builder, "\t%s\n", r)
+	}
+
+	fmt.Fprintf(&builder, "Headers:\n")
+	keys := make([]string, 0, len(d.Header))
+	for k := range d.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&builder, "\t%s: %s\n", k, strings.Join(d.Header[k], ", "))
+	}
+
+	fmt.Fprintf(&builder, "Links:\n")
+	for _, l := range d.Links {
+		if len(l.Reason) > 0 {
+			fmt.Fprintf(&builder, "\t[skipped] %s (%s)\n", l.Href, l.Reason)
+		} else {
+			fmt.Fprintf(&builder, "\t[kept]    %s -> %s\n", l.Href, l.URL)
+		}
+	}
+
+	return builder.String()
+}
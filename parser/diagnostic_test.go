@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnoseRedirectChainAndLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusFound)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/about">about</a><a href="/about">about</a><a href="/style.css">css</a><a href="/x#y">frag</a>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{
+		Timeout:           time.Second,
+		Distinct:          true,
+		IgnoreFragments:   true,
+		IgnoredExtensions: []string{".css"},
+	})
+
+	diagnostic, err := parser.Diagnose(server.URL + "/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diagnostic.FinalURL != server.URL+"/final" {
+		t.Fatalf("expected: %s, actual: %s", server.URL+"/final", diagnostic.FinalURL)
+	}
+
+	expectedChain := []string{server.URL + "/start", server.URL + "/middle"}
+	if strings.Join(diagnostic.RedirectChain, ",") != strings.Join(expectedChain, ",") {
+		t.Fatalf("expected: %v, actual: %v", expectedChain, diagnostic.RedirectChain)
+	}
+
+	if diagnostic.StatusCode != 200 || diagnostic.ContentType != "text/html" || diagnostic.BodySize <= 0 {
+		t.Fatalf("unexpected diagnostic: %+v", diagnostic)
+	}
+
+	expectedReasons := []string{"", "duplicate", "ignored extension .css", "contains fragment"}
+	if len(diagnostic.Links) != len(expectedReasons) {
+		t.Fatalf("expected len: %d, actual len: %d", len(expectedReasons), len(diagnostic.Links))
+	}
+
+	for i, l := range diagnostic.Links {
+		if l.Reason != expectedReasons[i] {
+			t.Fatalf("expected reason: %q, actual reason: %q", expectedReasons[i], l.Reason)
+		}
+	}
+
+	if !strings.Contains(diagnostic.String(), "[kept]    /about") {
+		t.Fatal("expected kept link in output")
+	}
+}
+
+func TestDiagnoseKeepsPartialDiagnosticOnParseError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/truncated", http.StatusFound)
+	})
+	mux.HandleFunc("/truncated", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`<a href="/about">`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	diagnostic, err := getTestParser(ParserOptions{Timeout: time.Second}).Diagnose(server.URL + "/start")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if diagnostic.StatusCode != 200 || diagnostic.ContentType != "text/html" {
+		t.Fatalf("unexpected diagnostic: %+v", diagnostic)
+	}
+
+	if len(diagnostic.RedirectChain) != 1 || diagnostic.RedirectChain[0] != server.URL+"/start" {
+		t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/start"}, diagnostic.RedirectChain)
+	}
+}
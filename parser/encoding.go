@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const DefaultAcceptEncoding = "gzip, deflate"
+
+type decodingBody struct {
+	encoding string
+	source   io.ReadCloser
+	reader   io.Reader
+	err      error
+}
+
+func decodeBody(header http.Header, body io.ReadCloser) (io.ReadCloser, bool) {
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate":
+	default:
+		return body, false
+	}
+
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return &decodingBody{encoding: encoding, source: body}, true
+}
+
+func (b *decodingBody) Read(buf []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.open()
+	}
+
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(buf)
+}
+
+func (b *decodingBody) open() (io.Reader, error) {
+	if b.encoding != "deflate" {
+		return gzip.NewReader(b.source)
+	}
+
+	buffered := bufio.NewReader(b.source)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+func (b *decodingBody) Close() error {
+	if closer, ok := b.reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return b.source.Close()
+}
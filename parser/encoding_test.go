@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLinksDecodesCompressedBodies(t *testing.T) {
+	page := []byte(`<a href="/about">about</a><a href="/blog">blog</a>`)
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"deflate-raw": func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		},
+	}
+
+	for name, encoder := range encoders {
+		var buf bytes.Buffer
+		writer := encoder(&buf)
+		writer.Write(page)
+		writer.Close()
+
+		var acceptEncoding string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acceptEncoding = r.Header.Get("Accept-Encoding")
+			encoding := name
+			if name == "deflate-raw" {
+				encoding = "deflate"
+			}
+			w.Header().Set("Content-Encoding", encoding)
+			w.Write(buf.Bytes())
+		}))
+
+		output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if acceptEncoding != DefaultAcceptEncoding {
+			t.Fatalf("expected: %s, actual: %s", DefaultAcceptEncoding, acceptEncoding)
+		}
+
+		expected := []string{server.URL + "/about", server.URL + "/blog"}
+		if !slices.Equal(output.Links, expected) {
+			t.Fatalf("%s expected: %v, actual: %v", name, expected, output.Links)
+		}
+	}
+}
+
+func TestDecodeBodyIdentity(t *testing.T) {
+	header := http.Header{}
+	body := io.NopCloser(bytes.NewReader([]byte("plain")))
+	decoded, ok := decodeBody(header, body)
+	if ok || decoded != body {
+		t.Fatal("expected an unencoded body to be returned unchanged")
+	}
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func (p *Parser) probe(ctx context.Context, input string, url url.URL) (ParserOutput, bool) {
+	check, err := p.head(ctx, input, &url, unprobed)
+	if err != nil || check.StatusCode < http.StatusOK || check.StatusCode >= http.StatusMultipleChoices {
+		hclog.Default().Debug("head probe inconclusive, falling back to get", "input", input, "status", check.Status, "error", err)
+		return ParserOutput{}, false
+	}
+
+	output := ParserOutput{Status: check.Status, StatusCode: check.StatusCode, Latency: check.Latency}
+	if !p.parseable(check.ContentType) {
+		hclog.Default().Debug("skipping unparseable content type", "input", input, "contentType", check.ContentType)
+		return output, true
+	}
+
+	if p.opts.MaxBodyBytes > 0 && check.ContentLength > p.opts.MaxBodyBytes {
+		hclog.Default().Debug("skipping oversized response", "input", input, "contentLength", check.ContentLength, "max", p.opts.MaxBodyBytes)
+		return output, true
+	}
+
+	return ParserOutput{}, false
+}
+
+func unprobed(_ context.Context, input string, _ *url.URL) (StatusCheck, error) {
+	return StatusCheck{URL: input}, nil
+}
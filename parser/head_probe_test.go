@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbeWithHeadSkipsImage(t *testing.T) {
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+			w.Write([]byte("png"))
+		}
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, ProbeWithHead: true}).ParseLinks(server.URL + "/logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d", http.StatusOK, output.StatusCode)
+	}
+
+	if gets.Load() != 0 {
+		t.Fatalf("expected: %d, actual: %d", 0, gets.Load())
+	}
+}
+
+func TestProbeWithHeadSkipsOversized(t *testing.T) {
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", "100")
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+			w.Write([]byte(strings.Repeat("x", 100)))
+		}
+	}))
+	defer server.Close()
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second, ProbeWithHead: true, MaxBodyBytes: 10}).ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if gets.Load() != 0 {
+		t.Fatalf("expected: %d, actual: %d", 0, gets.Load())
+	}
+}
+
+func TestProbeWithHeadFallsBackToGet(t *testing.T) {
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		gets.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/a">a</a>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, ProbeWithHead: true}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gets.Load() != 1 || len(output.Links) != 1 {
+		t.Fatalf("expected: %d %d, actual: %d %d", 1, 1, gets.Load(), len(output.Links))
+	}
+}
@@ -2,41 +2,145 @@ package parser
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/go-hclog"
 	"golang.org/x/net/html"
+	"golang.org/x/time/rate"
 )
 
+const DefaultAccept = "text/html,application/xhtml+xml"
+
+var DefaultParseableContentTypes = []string{"text/html", "application/xhtml+xml"}
+
+const DefaultUserAgent = "monzo-crawler/1.0"
+
+const DefaultRetryBackoff = time.Millisecond * 500
+
+const DefaultMaxRedirects = 10
+
+var inlineJsUrlPattern = regexp.MustCompile(`['"]((?:https?://|\.{0,2}/)[^'"\s]*)['"]`)
+
+var inlineJsAttributes = []string{"onclick", "onmousedown"}
+
+var cssUrlPattern = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+
+var headOnlyRels = []string{"canonical", "alternate", "prev", "next", "stylesheet"}
+
 type ParserOptions struct {
-	Timeout           time.Duration
-	SameSubdomain     bool
-	Distinct          bool
-	IgnoreFragments   bool
-	IgnoredExtensions []string
-	IgnoredPaths      []string
+	Timeout               time.Duration
+	SameSubdomain         bool
+	Distinct              bool
+	IgnoreFragments       bool
+	IgnoredExtensions     []string
+	IgnoredPaths          []string
+	DenyPathPrefixes      []string
+	DenyHosts             []string
+	IncludeRegex          string
+	ExcludeRegex          string
+	CanonicalizeScheme    bool
+	HTTPCacheDir          string
+	SoftErrorMarkers      []string
+	ParseInlineJSLinks    bool
+	MaxInFlightRequests   int
+	Accept                string
+	CookieFile            string
+	HostAliases           map[string]string
+	FollowPagination      bool
+	FollowMetaRefresh     bool
+	RecordExternal        bool
+	RequireContentLength  bool
+	MeasureUnknownBodies  bool
+	ScopeGlobs            []string
+	HeadOnly              bool
+	HashContent           bool
+	CollectAssets         bool
+	ParseCSSUrls          bool
+	UserAgent             string
+	RespectNofollow       bool
+	RequestsPerSecond     float64
+	MaxRetries            int
+	RetryBackoff          time.Duration
+	MaxRedirects          int
+	NormalizeQuery        bool
+	AllowedDomains        []string
+	StripQueryParams      []string
+	Headers               map[string]string
+	HeadersPerHost        map[string]map[string]string
+	ParseableContentTypes []string
+	MaxBodyBytes          int64
+	ProbeWithHead         bool
+	BasicAuthUser         string
+	BasicAuthPass         string
+	BasicAuthHosts        []string
+	InsecureSkipVerify    bool
+	ProxyURL              string
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DisableKeepAlives     bool
+	Client                *http.Client
+	Cache                 Cache
 }
 
 type Parser struct {
-	client *http.Client
-	opts   ParserOptions
+	client     *http.Client
+	cache      Cache
+	inFlight   chan struct{}
+	scope      []*regexp.Regexp
+	include    *regexp.Regexp
+	exclude    *regexp.Regexp
+	downloaded atomic.Int64
+	limiters   map[string]*rate.Limiter
+	paused     map[string]time.Time
+	limitLock  sync.Mutex
+	opts       ParserOptions
 }
 
 type ParserOutput struct {
-	Links      []string
-	Status     string
-	StatusCode int
+	Links         []string
+	ExternalLinks []string
+	Canonical     string
+	Title         string
+	Status        string
+	StatusCode    int
+	SoftError     string
+	ContentHash   string
+	Pagination    []string
+	Assets        []string
+	CSSAssets     []string
+	BodySize      int64
+	Latency       time.Duration
 }
 
 type SimpleHttpResponse struct {
-	Body       io.Reader
-	Status     string
-	StatusCode int
-	Header     http.Header
+	Body          io.Reader
+	Status        string
+	StatusCode    int
+	Header        http.Header
+	URL           string
+	RedirectChain []string
+	ContentLength int64
+	Latency       time.Duration
+	Cached        bool
 }
 
 func SanitiseUrl(rawUrl string) (string, error) {
@@ -45,129 +149,900 @@ func SanitiseUrl(rawUrl string) (string, error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("%s://%s%s", url.Scheme, url.Host, strings.TrimSuffix(url.Path, "/")), nil
+	return fmt.Sprintf("%s://%s%s", url.Scheme, normalizedHost(url), strings.TrimSuffix(url.Path, "/")), nil
 }
 
-func NewParser(opts ParserOptions) *Parser {
-	return &Parser{
-		client: http.DefaultClient,
+func NewParser(opts ParserOptions) (*Parser, error) {
+	client, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Parser{
+		client: client,
 		opts:   opts,
 	}
+
+	if len(opts.CookieFile) > 0 {
+		jar, err := loadCookieFile(opts.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		p.client.Jar = jar
+	}
+
+	if opts.Cache != nil {
+		p.cache = opts.Cache
+	} else if len(opts.HTTPCacheDir) > 0 {
+		p.cache = newHttpCache(opts.HTTPCacheDir)
+	}
+
+	if opts.MaxInFlightRequests > 0 {
+		p.inFlight = make(chan struct{}, opts.MaxInFlightRequests)
+	}
+
+	scope, err := compileGlobs(opts.ScopeGlobs)
+	if err != nil {
+		return nil, err
+	}
+	p.scope = scope
+
+	if len(opts.IncludeRegex) > 0 {
+		include, err := regexp.Compile(opts.IncludeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include regex %s: %w", opts.IncludeRegex, err)
+		}
+		p.include = include
+	}
+
+	if len(opts.ExcludeRegex) > 0 {
+		exclude, err := regexp.Compile(opts.ExcludeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude regex %s: %w", opts.ExcludeRegex, err)
+		}
+		p.exclude = exclude
+	}
+
+	return p, nil
+}
+
+func newClient(opts ParserOptions) (*http.Client, error) {
+	if opts.Client != nil {
+		client := *opts.Client
+		client.CheckRedirect = noRedirects
+		return &client, nil
+	}
+
+	transport, err := newTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{CheckRedirect: noRedirects, Transport: transport}, nil
+}
+
+func newTransport(opts ParserOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.InsecureSkipVerify {
+		hclog.Default().Warn("tls certificate verification disabled")
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	if opts.DialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+
+	if opts.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		transport.MaxIdleConns = max(transport.MaxIdleConns, opts.MaxIdleConnsPerHost)
+	}
+
+	if opts.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+
+	if len(opts.ProxyURL) > 0 {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %s: %w", opts.ProxyURL, err)
+		}
+
+		if !slices.Contains([]string{"http", "https", "socks5"}, proxy.Scheme) || len(proxy.Host) <= 0 {
+			return nil, fmt.Errorf("invalid proxy url %s: expected http, https or socks5 scheme and a host", opts.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	return transport, nil
 }
 
 func (p *Parser) ParseLinks(input string) (ParserOutput, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	return p.ParseLinksContext(context.Background(), input)
+}
+
+func (p *Parser) ParseLinksContext(ctx context.Context, input string) (ParserOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
 	defer cancel()
 
-	url, baseUrl, err := getUrl(input)
+	url, _, err := getUrl(input)
 	if err != nil {
 		return ParserOutput{}, err
 	}
 
+	if p.opts.ProbeWithHead {
+		if output, skip := p.probe(ctx, input, *url); skip {
+			return output, nil
+		}
+	}
+
 	response, err := p.get(ctx, *url)
 	if err != nil {
 		return ParserOutput{}, err
 	}
 
-	links, err := parseLinksFromHtmlBody(response.Body)
+	defer closeBody(response.Body)
+
+	if !p.parseable(response.Header.Get("Content-Type")) {
+		hclog.Default().Debug("skipping unparseable content type", "input", input, "contentType", response.Header.Get("Content-Type"))
+		return ParserOutput{Status: response.Status, StatusCode: response.StatusCode, Latency: response.Latency}, nil
+	}
+
+	var limited *truncatingReader
+	reader := response.Body
+	if p.opts.MaxBodyBytes > 0 {
+		limited = &truncatingReader{reader: reader, remaining: p.opts.MaxBodyBytes}
+		reader = limited
+	}
+
+	hash := sha256.New()
+	if p.opts.HashContent {
+		reader = io.TeeReader(reader, hash)
+	}
+
+	body := &countingReader{reader: reader}
+	doc, err := parseLinksFromHtmlBody(body, p.opts)
 	if err != nil {
-		return ParserOutput{Status: response.Status, StatusCode: response.StatusCode}, err
+		return ParserOutput{Status: response.Status, StatusCode: response.StatusCode, BodySize: body.count, Latency: response.Latency}, err
+	}
+
+	var contentHash string
+	if p.opts.HashContent {
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			return ParserOutput{Status: response.Status, StatusCode: response.StatusCode, BodySize: body.count, Latency: response.Latency}, err
+		}
+		contentHash = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	if limited != nil && limited.truncated {
+		hclog.Default().Warn("response body truncated", "input", input, "max", p.opts.MaxBodyBytes)
+	}
+
+	baseUrl := url.String()
+	if len(response.URL) > 0 {
+		baseUrl = response.URL
+	}
+
+	if len(doc.base) > 0 {
+		doc.resolveBase(baseUrl)
 	}
 
 	return ParserOutput{
-		Links:      p.filterLinks(links, baseUrl),
-		Status:     response.Status,
-		StatusCode: response.StatusCode,
+		Links:         p.filterLinks(doc.links, baseUrl),
+		ExternalLinks: p.filterExternalLinks(doc.links, baseUrl),
+		Canonical:     p.filterCanonical(doc.canonical, baseUrl),
+		Title:         doc.title,
+		Status:        response.Status,
+		StatusCode:    response.StatusCode,
+		SoftError:     doc.softError,
+		ContentHash:   contentHash,
+		Pagination:    p.filterLinks(doc.pagination, baseUrl),
+		Assets:        p.filterAssets(doc.links, doc.assets, baseUrl),
+		CSSAssets:     p.filterCSSAssets(doc.cssAssets, baseUrl),
+		BodySize:      body.count,
+		Latency:       response.Latency,
 	}, err
 }
 
-func (p *Parser) get(ctx context.Context, url url.URL) (SimpleHttpResponse, error) {
-	res, err := p.handleRequest(ctx, url)
+func (p *Parser) parseable(contentType string) bool {
+	if len(contentType) <= 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return SimpleHttpResponse{}, err
+		return false
+	}
+
+	types := p.opts.ParseableContentTypes
+	if len(types) <= 0 {
+		types = DefaultParseableContentTypes
+	}
+
+	for _, t := range types {
+		if strings.EqualFold(mediaType, strings.TrimSpace(t)) {
+			return true
+		}
 	}
+	return false
+}
 
-	if res.StatusCode == 301 || res.StatusCode == 302 {
-		redirectUrl, err := url.Parse(res.Header.Get("Location"))
+func (p *Parser) get(ctx context.Context, url url.URL) (SimpleHttpResponse, error) {
+	if p.cache == nil {
+		return p.fetchWithMethod(ctx, http.MethodGet, url)
+	}
+	return p.cached(ctx, url, time.Now())
+}
+
+func (p *Parser) fetchWithMethod(ctx context.Context, method string, url url.URL) (SimpleHttpResponse, error) {
+	return p.fetchWithHeaders(ctx, method, url, nil)
+}
+
+func (p *Parser) fetchWithHeaders(ctx context.Context, method string, url url.URL, headers map[string]string) (SimpleHttpResponse, error) {
+	maxRedirects := p.opts.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	var chain []string
+	var latency time.Duration
+	seen := map[string]bool{url.String(): true}
+	for {
+		res, err := p.handleRequestWithMethod(ctx, method, url, headers)
 		if err != nil {
 			return SimpleHttpResponse{}, err
 		}
 
-		redirectRes, err := p.handleRequest(ctx, *redirectUrl)
+		res.RedirectChain = append(chain, res.RedirectChain...)
+		res.Latency += latency
+		if !isRedirect(res.StatusCode) {
+			return res, nil
+		}
+		closeBody(res.Body)
+
+		if len(chain) >= maxRedirects {
+			return SimpleHttpResponse{}, fmt.Errorf("stopped after %d redirects at %s", maxRedirects, res.URL)
+		}
+
+		next, err := url.Parse(res.Header.Get("Location"))
 		if err != nil {
 			return SimpleHttpResponse{}, err
 		}
 
-		return redirectRes, nil
+		if seen[next.String()] {
+			return SimpleHttpResponse{}, fmt.Errorf("redirect loop from %s to %s", res.URL, next.String())
+		}
+		seen[next.String()] = true
+
+		chain = append(res.RedirectChain, res.URL)
+		latency = res.Latency
+		url = *next
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
 	}
+	return false
+}
+
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
 
-	return res, nil
+func (p *Parser) CloseIdleConnections() {
+	p.client.CloseIdleConnections()
+}
+
+func (p *Parser) DownloadedBytes() int64 {
+	return p.downloaded.Load()
 }
 
 func (p *Parser) handleRequest(ctx context.Context, url url.URL) (SimpleHttpResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	return p.handleRequestWithMethod(ctx, http.MethodGet, url, nil)
+}
+
+func (p *Parser) handleRequestWithMethod(ctx context.Context, method string, url url.URL, headers map[string]string) (SimpleHttpResponse, error) {
+	backoff := p.opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := p.doRequest(ctx, method, url, headers)
+		if attempt >= p.opts.MaxRetries || ctx.Err() != nil || !shouldRetry(res, err) {
+			return res, err
+		}
+		closeBody(res.Body)
+
+		delay := backoff << attempt
+		if retryAfter, ok := parseRetryAfter(res.Header, time.Now()); ok && res.StatusCode == http.StatusTooManyRequests {
+			p.pauseHost(url.Host, retryAfter)
+			delay = retryAfter
+		}
+		hclog.Default().Debug("retrying request",
+			"input", url.String(),
+			"status", res.Status,
+			"error", err,
+			"attempt", attempt+1,
+			"delay", delay,
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return SimpleHttpResponse{}, ctx.Err()
+		}
+	}
+}
+
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if len(value) <= 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
 	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+func shouldRetry(res SimpleHttpResponse, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) doRequest(ctx context.Context, method string, url url.URL, headers map[string]string) (SimpleHttpResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url.String(), nil)
+	if err != nil {
+		return SimpleHttpResponse{}, err
+	}
+
+	accept := p.opts.Accept
+	if len(accept) <= 0 {
+		accept = DefaultAccept
+	}
+	req.Header.Set("Accept", accept)
+
+	userAgent := p.opts.UserAgent
+	if len(userAgent) <= 0 {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if method != http.MethodHead {
+		req.Header.Set("Accept-Encoding", DefaultAcceptEncoding)
+	}
+	p.setHeaders(req)
+	setHeaders(req, headers)
+
+	if err := p.waitForHost(ctx, url.Host); err != nil {
 		return SimpleHttpResponse{}, err
 	}
 
+	release := func() {}
+	if p.inFlight != nil {
+		select {
+		case p.inFlight <- struct{}{}:
+			release = func() { <-p.inFlight }
+		case <-ctx.Done():
+			return SimpleHttpResponse{}, ctx.Err()
+		}
+	}
+
+	start := time.Now()
 	res, err := p.client.Do(req)
 	if err != nil {
+		release()
 		return SimpleHttpResponse{}, err
 	}
+	latency := time.Since(start)
+
+	var redirectChain []string
+	for r := res.Request.Response; r != nil; r = r.Request.Response {
+		redirectChain = append([]string{r.Request.URL.String()}, redirectChain...)
+	}
+
+	body, decoded := decodeBody(res.Header, &releasingBody{ReadCloser: res.Body, release: release, downloaded: &p.downloaded})
+	contentLength := res.ContentLength
+	if decoded {
+		contentLength = -1
+	}
 
 	return SimpleHttpResponse{
-		Body:       res.Body,
-		Status:     res.Status,
-		StatusCode: res.StatusCode,
-		Header:     res.Header,
+		Body:          body,
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		URL:           res.Request.URL.String(),
+		RedirectChain: redirectChain,
+		ContentLength: contentLength,
+		Latency:       latency,
 	}, nil
 }
 
+func (p *Parser) waitForHost(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	p.limitLock.Lock()
+	pausedFor := time.Until(p.paused[host])
+	var limiter *rate.Limiter
+	if p.opts.RequestsPerSecond > 0 {
+		if p.limiters == nil {
+			p.limiters = make(map[string]*rate.Limiter)
+		}
+		limiter = p.limiters[host]
+		if limiter == nil {
+			limiter = rate.NewLimiter(rate.Limit(p.opts.RequestsPerSecond), 1)
+			p.limiters[host] = limiter
+		}
+	}
+	p.limitLock.Unlock()
+
+	if pausedFor > 0 {
+		select {
+		case <-time.After(pausedFor):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+func (p *Parser) pauseHost(host string, delay time.Duration) {
+	p.limitLock.Lock()
+	defer p.limitLock.Unlock()
+
+	if p.paused == nil {
+		p.paused = make(map[string]time.Time)
+	}
+
+	host = strings.ToLower(host)
+	if until := time.Now().Add(delay); until.After(p.paused[host]) {
+		p.paused[host] = until
+	}
+}
+
+var managedHeaders = []string{"Host", "Content-Length", "Connection", "Transfer-Encoding"}
+
+func (p *Parser) setHeaders(req *http.Request) {
+	setHeaders(req, p.opts.Headers)
+
+	headers, ok := p.opts.HeadersPerHost[strings.ToLower(req.URL.Host)]
+	if !ok {
+		headers = p.opts.HeadersPerHost[strings.ToLower(req.URL.Hostname())]
+	}
+
+	setHeaders(req, headers)
+
+	if len(p.opts.BasicAuthUser) > 0 && p.basicAuthHost(req.URL) {
+		req.SetBasicAuth(p.opts.BasicAuthUser, p.opts.BasicAuthPass)
+	}
+}
+
+func setHeaders(req *http.Request, headers map[string]string) {
+	for name, value := range headers {
+		if slices.Contains(managedHeaders, http.CanonicalHeaderKey(name)) {
+			hclog.Default().Debug("ignoring managed header", "name", name)
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
+
+func (p *Parser) basicAuthHost(u *url.URL) bool {
+	for _, host := range p.opts.BasicAuthHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+type truncatingReader struct {
+	reader    io.Reader
+	remaining int64
+	truncated bool
+}
+
+func (r *truncatingReader) Read(b []byte) (int, error) {
+	if r.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := r.reader.Read(probe[:]); n > 0 {
+			r.truncated = true
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+	n, err := r.reader.Read(b)
+	r.remaining -= int64(n)
+	return n, err
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	once       sync.Once
+	release    func()
+	downloaded *atomic.Int64
+}
+
+func (b *releasingBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	b.downloaded.Add(int64(n))
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+func closeBody(body io.Reader) {
+	if closer, ok := body.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
 func (p *Parser) filterLinks(links []string, baseUrl string) []string {
 	var filteredLinks []string
-loop:
 	for _, l := range links {
-		if p.opts.IgnoreFragments && strings.Contains(l, "#") {
+		link, reason := p.filterLink(l, baseUrl)
+		if len(reason) > 0 {
 			continue
 		}
 
-		if len(p.opts.IgnoredExtensions) > 0 {
-			for _, ext := range p.opts.IgnoredExtensions {
-				if strings.HasSuffix(l, ext) {
-					continue loop
-				}
-			}
+		filteredLinks = append(filteredLinks, link)
+	}
+
+	if p.opts.Distinct {
+		filteredLinks = distinctLinks(filteredLinks)
+	}
+
+	return filteredLinks
+}
+
+func (p *Parser) filterCanonical(canonical string, baseUrl string) string {
+	if len(canonical) <= 0 {
+		return ""
+	}
+
+	link, reason := p.filterLink(canonical, baseUrl)
+	if len(reason) > 0 {
+		hclog.Default().Debug("ignoring canonical link", "link", canonical, "reason", reason)
+		return ""
+	}
+	return link
+}
+
+func (p *Parser) filterExternalLinks(links []string, baseUrl string) []string {
+	if !p.opts.RecordExternal {
+		return nil
+	}
+
+	var external []string
+	for _, l := range links {
+		if link, ok := p.externalLink(strings.TrimSpace(l), baseUrl); ok {
+			external = append(external, link)
 		}
+	}
 
-		if len(p.opts.IgnoredPaths) > 0 {
-			for _, path := range p.opts.IgnoredPaths {
-				if strings.Contains(l, path) {
-					continue loop
-				}
-			}
+	return distinctLinks(external)
+}
+
+func (p *Parser) externalLink(l string, baseUrl string) (string, bool) {
+	if len(l) <= 0 {
+		return "", false
+	}
+
+	l, err := resolveLink(l, baseUrl)
+	if err != nil {
+		return "", false
+	}
+
+	if len(p.opts.HostAliases) > 0 {
+		l = applyHostAlias(l, p.opts.HostAliases)
+		baseUrl = applyHostAlias(baseUrl, p.opts.HostAliases)
+	}
+
+	if p.opts.CanonicalizeScheme {
+		l = canonicalizeScheme(l, baseUrl)
+	}
+
+	if len(p.opts.AllowedDomains) > 0 {
+		if allowedDomain(l, p.opts.AllowedDomains) {
+			return "", false
 		}
+	} else if !p.opts.SameSubdomain || sameOrigin(l, baseUrl) {
+		return "", false
+	}
 
-		if strings.HasPrefix(l, "/") {
-			l = fmt.Sprintf("%s%s", baseUrl, l)
+	sanitisedLink, err := p.NormalizeUrl(l)
+	if err != nil {
+		return "", false
+	}
+
+	return sanitisedLink, true
+}
+
+func (p *Parser) filterAssets(links []string, sources []string, baseUrl string) []string {
+	if !p.opts.CollectAssets {
+		return nil
+	}
+
+	var candidates []string
+	for _, l := range links {
+		l = strings.TrimSpace(l)
+		if len(p.ignoredExtension(l)) > 0 {
+			candidates = append(candidates, l)
 		}
+	}
+
+	return p.resolveAssets(append(candidates, sources...), baseUrl)
+}
 
-		if p.opts.SameSubdomain && !strings.HasPrefix(l, baseUrl) {
+func (p *Parser) filterCSSAssets(sources []string, baseUrl string) []string {
+	if !p.opts.ParseCSSUrls {
+		return nil
+	}
+
+	return p.resolveAssets(sources, baseUrl)
+}
+
+func (p *Parser) resolveAssets(sources []string, baseUrl string) []string {
+	var assets []string
+	for _, l := range sources {
+		asset, reason := p.resolveFilteredLink(strings.TrimSpace(l), baseUrl)
+		if len(reason) > 0 {
 			continue
 		}
 
-		sanitisedLink, err := SanitiseUrl(l)
-		if err != nil {
-			continue
+		assets = append(assets, asset)
+	}
+
+	return distinctLinks(assets)
+}
+
+func (p *Parser) ignoredExtension(l string) string {
+	for _, ext := range p.opts.IgnoredExtensions {
+		if strings.HasSuffix(l, ext) {
+			return ext
 		}
+	}
+	return ""
+}
 
-		filteredLinks = append(filteredLinks, sanitisedLink)
+func (p *Parser) filterLink(l string, baseUrl string) (string, string) {
+	l = strings.TrimSpace(l)
+	if len(l) <= 0 {
+		return "", "empty link"
 	}
 
-	if p.opts.Distinct {
-		filteredLinks = distinctLinks(filteredLinks)
+	if p.opts.IgnoreFragments && strings.Contains(l, "#") {
+		return "", "contains fragment"
 	}
 
-	return filteredLinks
+	if ext := p.ignoredExtension(l); len(ext) > 0 {
+		return "", fmt.Sprintf("ignored extension %s", ext)
+	}
+
+	return p.resolveFilteredLink(l, baseUrl)
+}
+
+func (p *Parser) resolveFilteredLink(l string, baseUrl string) (string, string) {
+	for _, path := range p.opts.IgnoredPaths {
+		if strings.Contains(l, path) {
+			return "", fmt.Sprintf("ignored path %s", path)
+		}
+	}
+
+	l, err := resolveLink(l, baseUrl)
+	if err != nil {
+		hclog.Default().Debug("skipping unresolvable link", "link", l, "base", baseUrl, "error", err)
+		return "", err.Error()
+	}
+
+	if len(p.opts.HostAliases) > 0 {
+		l = applyHostAlias(l, p.opts.HostAliases)
+		baseUrl = applyHostAlias(baseUrl, p.opts.HostAliases)
+	}
+
+	if p.opts.CanonicalizeScheme {
+		l = canonicalizeScheme(l, baseUrl)
+	}
+
+	if len(p.opts.DenyHosts) > 0 && allowedDomain(l, p.opts.DenyHosts) {
+		return "", "denied host"
+	}
+
+	if prefix := deniedPathPrefix(l, p.opts.DenyPathPrefixes); len(prefix) > 0 {
+		return "", fmt.Sprintf("denied path prefix %s", prefix)
+	}
+
+	if len(p.opts.AllowedDomains) > 0 {
+		if !allowedDomain(l, p.opts.AllowedDomains) {
+			return "", "outside allowed domains"
+		}
+	} else if p.opts.SameSubdomain && !sameOrigin(l, baseUrl) {
+		return "", fmt.Sprintf("outside subdomain %s", baseUrl)
+	}
+
+	sanitisedLink, err := p.NormalizeUrl(l)
+	if err != nil {
+		hclog.Default().Debug("skipping invalid link", "link", l, "error", err)
+		return "", err.Error()
+	}
+
+	if len(p.scope) > 0 && !matchesAnyGlob(sanitisedLink, p.scope) {
+		return "", "outside scope globs"
+	}
+
+	if p.include != nil && !p.include.MatchString(sanitisedLink) {
+		return "", "not matching include regex"
+	}
+
+	if p.exclude != nil && p.exclude.MatchString(sanitisedLink) {
+		return "", "matching exclude regex"
+	}
+
+	return sanitisedLink, ""
+}
+
+func resolveLink(link string, baseUrl string) (string, error) {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link, err
+	}
+
+	if ref.IsAbs() {
+		return link, nil
+	}
+
+	base, _, err := getUrl(baseUrl)
+	if err != nil {
+		return link, fmt.Errorf("relative link without valid base url: %w", err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (p *Parser) CanonicalHost(link string) string {
+	if len(p.opts.HostAliases) <= 0 {
+		return link
+	}
+	return applyHostAlias(link, p.opts.HostAliases)
+}
+
+func applyHostAlias(link string, aliases map[string]string) string {
+	parsedLink, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	canonical, ok := aliases[strings.ToLower(parsedLink.Hostname())]
+	if !ok {
+		return link
+	}
+
+	if port := parsedLink.Port(); len(port) > 0 {
+		parsedLink.Host = fmt.Sprintf("%s:%s", canonical, port)
+	} else {
+		parsedLink.Host = canonical
+	}
+	return parsedLink.String()
+}
+
+func sameOrigin(link string, baseUrl string) bool {
+	parsedLink, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(parsedLink.Scheme, base.Scheme) && originHost(parsedLink) == originHost(base)
+}
+
+func originHost(u *url.URL) string {
+	port := u.Port()
+	if len(port) <= 0 {
+		switch strings.ToLower(u.Scheme) {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	return strings.ToLower(u.Hostname()) + ":" + port
+}
+
+func allowedDomain(link string, domains []string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+		if len(domain) > 0 && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+func deniedPathPrefix(link string, prefixes []string) string {
+	if len(prefixes) <= 0 {
+		return ""
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	for _, prefix := range prefixes {
+		prefix = "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+		if u.Path == prefix || strings.HasPrefix(u.Path, strings.TrimSuffix(prefix, "/")+"/") {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func canonicalizeScheme(link string, baseUrl string) string {
+	base, err := url.Parse(baseUrl)
+	if err != nil || base.Scheme != "https" {
+		return link
+	}
+
+	parsedLink, err := url.Parse(link)
+	if err != nil || parsedLink.Scheme != "http" || parsedLink.Host != base.Host {
+		return link
+	}
+
+	parsedLink.Scheme = "https"
+	return parsedLink.String()
 }
 
 func distinctLinks(links []string) []string {
@@ -201,15 +1076,60 @@ func getUrl(rawUrl string) (*url.URL, string, error) {
 		return nil, "", fmt.Errorf("missing or invalid scheme for input %s", rawUrl)
 	}
 
-	if parsedUrl.Host == "" {
+	if parsedUrl.Hostname() == "" {
 		return nil, "", fmt.Errorf("missing host for input %s", rawUrl)
 	}
 
 	return parsedUrl, fmt.Sprintf("%s://%s", parsedUrl.Scheme, parsedUrl.Host), nil
 }
 
-func parseLinksFromHtmlBody(reader io.Reader) ([]string, error) {
-	var links []string
+type htmlDocument struct {
+	links      []string
+	pagination []string
+	assets     []string
+	cssAssets  []string
+	softError  string
+	base       string
+	canonical  string
+	title      string
+}
+
+func (doc *htmlDocument) resolveBase(pageUrl string) {
+	base, err := resolveLink(doc.base, pageUrl)
+	if err != nil {
+		hclog.Default().Debug("ignoring invalid base href", "base", doc.base, "error", err)
+		return
+	}
+
+	doc.links = resolveLinks(doc.links, base)
+	doc.pagination = resolveLinks(doc.pagination, base)
+	doc.assets = resolveLinks(doc.assets, base)
+	doc.cssAssets = resolveLinks(doc.cssAssets, base)
+	if r, err := resolveLink(doc.canonical, base); err == nil && len(doc.canonical) > 0 {
+		doc.canonical = r
+	}
+}
+
+func resolveLinks(links []string, base string) []string {
+	resolved := make([]string, len(links))
+	for i, l := range links {
+		l = strings.TrimSpace(l)
+		if len(l) <= 0 {
+			continue
+		}
+
+		if r, err := resolveLink(l, base); err == nil {
+			l = r
+		}
+		resolved[i] = l
+	}
+	return resolved
+}
+
+func parseLinksFromHtmlBody(reader io.Reader, opts ParserOptions) (htmlDocument, error) {
+	var doc htmlDocument
+	var inStyle, inTitle, seenTitle bool
+	var title strings.Builder
 	tokenizer := html.NewTokenizer(reader)
 
 	for {
@@ -218,19 +1138,233 @@ func parseLinksFromHtmlBody(reader io.Reader) ([]string, error) {
 		switch {
 		case tokenType == html.ErrorToken:
 			if err := tokenizer.Err(); err != io.EOF {
-				return nil, err
+				return htmlDocument{}, err
+			}
+
+			return doc, nil
+		case tokenType == html.EndTagToken:
+			t := tokenizer.Token()
+			if opts.HeadOnly && t.Data == "head" {
+				return doc, nil
 			}
 
-			return links, nil
-		case tokenType == html.StartTagToken:
+			if t.Data == "style" {
+				inStyle = false
+			}
+
+			if t.Data == "title" && inTitle {
+				inTitle = false
+				doc.title = strings.Join(strings.Fields(title.String()), " ")
+			}
+		case tokenType == html.StartTagToken || tokenType == html.SelfClosingTagToken:
 			t := tokenizer.Token()
-			if t.Data == "a" {
+			if opts.HeadOnly && t.Data == "body" {
+				return doc, nil
+			}
+
+			if t.Data == "title" && tokenType == html.StartTagToken && !seenTitle {
+				inTitle, seenTitle = true, true
+			}
+
+			if t.Data == "base" && len(doc.base) <= 0 {
+				if href := parseHref(t.Attr); len(href) > 0 {
+					doc.base = strings.TrimSpace(href[0])
+				}
+			}
+
+			if t.Data == "meta" && opts.FollowMetaRefresh {
+				if target, ok := parseMetaRefresh(t.Attr); ok {
+					doc.links = append(doc.links, target)
+				}
+			}
+
+			if t.Data == "link" && len(doc.canonical) <= 0 && hasRel(t.Attr, "canonical") {
+				if href := parseHref(t.Attr); len(href) > 0 {
+					doc.canonical = strings.TrimSpace(href[0])
+				}
+			}
+
+			if t.Data == "link" {
+				if href, ok := parsePaginationLink(t.Attr); ok && opts.FollowPagination {
+					doc.links = append(doc.links, href)
+					doc.pagination = append(doc.pagination, href)
+				} else if opts.HeadOnly && hasRel(t.Attr, headOnlyRels...) {
+					doc.links = append(doc.links, parseHref(t.Attr)...)
+				}
+			}
+
+			if opts.CollectAssets {
+				doc.assets = append(doc.assets, parseAssetLinks(t)...)
+			}
+
+			if opts.ParseCSSUrls {
+				if t.Data == "style" && tokenType == html.StartTagToken {
+					inStyle = true
+				}
+
+				for _, a := range t.Attr {
+					if a.Key == "style" {
+						doc.cssAssets = append(doc.cssAssets, parseCSSUrls(a.Val)...)
+					}
+				}
+			}
+
+			if t.Data == "a" && !(opts.RespectNofollow && hasRel(t.Attr, "nofollow")) {
 				for _, a := range t.Attr {
 					if a.Key == "href" {
-						links = append(links, a.Val)
+						doc.links = append(doc.links, a.Val)
 					}
 				}
 			}
+
+			if opts.ParseInlineJSLinks {
+				doc.links = append(doc.links, parseInlineJsLinks(t.Attr)...)
+			}
+		case tokenType == html.TextToken:
+			text := string(tokenizer.Text())
+			if inTitle {
+				title.WriteString(text)
+			}
+
+			if inStyle {
+				doc.cssAssets = append(doc.cssAssets, parseCSSUrls(text)...)
+				continue
+			}
+
+			if len(doc.softError) > 0 || len(opts.SoftErrorMarkers) <= 0 {
+				continue
+			}
+
+			for _, marker := range opts.SoftErrorMarkers {
+				if strings.Contains(text, marker) {
+					doc.softError = marker
+					break
+				}
+			}
 		}
 	}
 }
+
+func parseAssetLinks(t html.Token) []string {
+	var key string
+	switch t.Data {
+	case "img", "script", "source", "video", "audio":
+		key = "src"
+	case "link":
+		if !hasRel(t.Attr, "stylesheet", "icon", "preload") {
+			return nil
+		}
+		key = "href"
+	default:
+		return nil
+	}
+
+	var assets []string
+	for _, a := range t.Attr {
+		if a.Key == key && len(a.Val) > 0 {
+			assets = append(assets, a.Val)
+		}
+	}
+	return assets
+}
+
+func parseCSSUrls(css string) []string {
+	var urls []string
+	for _, match := range cssUrlPattern.FindAllStringSubmatch(css, -1) {
+		if strings.HasPrefix(strings.ToLower(match[1]), "data:") {
+			continue
+		}
+		urls = append(urls, match[1])
+	}
+	return urls
+}
+
+func parseHref(attrs []html.Attribute) []string {
+	for _, a := range attrs {
+		if a.Key == "href" && len(a.Val) > 0 {
+			return []string{a.Val}
+		}
+	}
+	return nil
+}
+
+func hasRel(attrs []html.Attribute, rels ...string) bool {
+	for _, a := range attrs {
+		if a.Key != "rel" {
+			continue
+		}
+
+		for _, rel := range strings.Fields(a.Val) {
+			for _, r := range rels {
+				if strings.EqualFold(rel, r) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func parseMetaRefresh(attrs []html.Attribute) (string, bool) {
+	var refresh bool
+	var content string
+	for _, a := range attrs {
+		switch a.Key {
+		case "http-equiv":
+			refresh = strings.EqualFold(strings.TrimSpace(a.Val), "refresh")
+		case "content":
+			content = a.Val
+		}
+	}
+
+	if !refresh {
+		return "", false
+	}
+
+	_, target, ok := strings.Cut(content, ";")
+	if !ok {
+		_, target, ok = strings.Cut(content, ",")
+	}
+	target = strings.TrimSpace(target)
+	if !ok || len(target) < 3 || !strings.EqualFold(target[:3], "url") {
+		return "", false
+	}
+
+	target, ok = strings.CutPrefix(strings.TrimSpace(target[3:]), "=")
+	target = strings.Trim(strings.TrimSpace(target), `"'`)
+	return target, ok && len(target) > 0
+}
+
+func parsePaginationLink(attrs []html.Attribute) (string, bool) {
+	var href string
+	var paginated bool
+	for _, a := range attrs {
+		switch a.Key {
+		case "href":
+			href = a.Val
+		case "rel":
+			for _, rel := range strings.Fields(a.Val) {
+				if strings.EqualFold(rel, "prev") || strings.EqualFold(rel, "next") {
+					paginated = true
+				}
+			}
+		}
+	}
+
+	return href, paginated && len(href) > 0
+}
+
+func parseInlineJsLinks(attrs []html.Attribute) []string {
+	var links []string
+	for _, a := range attrs {
+		if !slices.Contains(inlineJsAttributes, strings.ToLower(a.Key)) {
+			continue
+		}
+
+		for _, match := range inlineJsUrlPattern.FindAllStringSubmatch(a.Val, -1) {
+			links = append(links, match[1])
+		}
+	}
+
+	return links
+}
@@ -1,9 +1,22 @@
 package parser
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/net/html"
 )
 
 func TestSanitiseUrlEmpty(t *testing.T) {
@@ -57,6 +70,13 @@ func TestSanitiseUrlRemovesTrailingSlash(t *testing.T) {
 	}
 }
 
+func TestSanitiseUrlMissingHostname(t *testing.T) {
+	_, err := SanitiseUrl("https://:443/about")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestParseLinksInvalidUrl(t *testing.T) {
 
 	_, err := getDefaultTestParser().ParseLinks("monzo")
@@ -83,6 +103,69 @@ func TestFilterLinksRelativePaths(t *testing.T) {
 	}
 }
 
+func TestFilterLinksDenyPathPrefixes(t *testing.T) {
+	links := []string{
+		"https://monzo.com/admin",
+		"https://monzo.com/admin/users",
+		"https://monzo.com/administration",
+		"https://monzo.com/blog/admin",
+	}
+
+	for _, prefixes := range [][]string{{"/admin"}, {"admin/"}} {
+		result := getTestParser(ParserOptions{DenyPathPrefixes: prefixes}).filterLinks(links, "https://monzo.com")
+
+		expected := []string{"https://monzo.com/administration", "https://monzo.com/blog/admin"}
+		if !slices.Equal(result, expected) {
+			t.Fatalf("expected: %v, actual: %v", expected, result)
+		}
+	}
+
+	result := getTestParser(ParserOptions{IgnoredPaths: []string{"admin"}}).filterLinks(links, "https://monzo.com")
+	if len(result) != 0 {
+		t.Fatalf("expected len: %d, actual len: %d", 0, len(result))
+	}
+}
+
+func TestFilterLinksIncludeExcludeRegex(t *testing.T) {
+	links := []string{
+		"https://x.com/blog/post",
+		"https://x.com/blog/report.pdf",
+		"https://x.com/about",
+		"https://x.com/docs/blog/",
+	}
+
+	result := getTestParser(ParserOptions{IncludeRegex: `^https://x\.com/blog/`, ExcludeRegex: `\.pdf$`}).filterLinks(links, "https://x.com")
+
+	expected := []string{"https://x.com/blog/post"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestNewParserInvalidRegex(t *testing.T) {
+	for _, opts := range []ParserOptions{{IncludeRegex: "("}, {ExcludeRegex: "[a-"}} {
+		if _, err := NewParser(opts); err == nil {
+			t.Fatalf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestFilterLinksDenyHosts(t *testing.T) {
+	links := []string{
+		"https://monzo.com/about",
+		"https://status.monzo.com/incidents",
+		"https://notmonzo.com/about",
+		"https://community.monzo.com/t/1",
+	}
+
+	result := getTestParser(ParserOptions{DenyHosts: []string{"status.monzo.com", "community.monzo.com"}}).filterLinks(links, "https://monzo.com")
+
+	expected := []string{"https://monzo.com/about", "https://notmonzo.com/about"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
 func TestFilterLinksSameSubdomain(t *testing.T) {
 	links := []string{
 		"https://monzo.com/about",
@@ -169,12 +252,1231 @@ func TestFilterLinksDistinct(t *testing.T) {
 	}
 }
 
-func getDefaultTestParser() *Parser {
-	return getTestParser(ParserOptions{})
+func TestFilterLinksCanonicalizeScheme(t *testing.T) {
+	links := []string{
+		"http://monzo.com/about",
+		"https://monzo.com/about",
+	}
+
+	result := getTestParser(ParserOptions{CanonicalizeScheme: true, Distinct: true}).
+		filterLinks(links, "https://monzo.com")
+	if len(result) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(result))
+	}
+
+	if result[0] != "https://monzo.com/about" {
+		t.Fatalf("expected: %s, actual: %s", "https://monzo.com/about", result[0])
+	}
+
+	result = getTestParser(ParserOptions{CanonicalizeScheme: false, Distinct: true}).
+		filterLinks(links, "https://monzo.com")
+	if len(result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(result))
+	}
 }
 
-func getTestParser(opts ParserOptions) *Parser {
-	parser := NewParser(opts)
-	parser.client = new(http.Client)
-	return parser
+func TestFilterLinksCanonicalizeSchemeHttpBase(t *testing.T) {
+	links := []string{
+		"http://monzo.com/about",
+		"https://monzo.com/about",
+	}
+
+	result := getTestParser(ParserOptions{CanonicalizeScheme: true, Distinct: true}).
+		filterLinks(links, "http://monzo.com")
+	if len(result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(result))
+	}
+}
+
+func TestFilterLinksCanonicalizeSchemeOtherHost(t *testing.T) {
+	links := []string{
+		"http://instagram.com/monzo",
+	}
+
+	result := getTestParser(ParserOptions{CanonicalizeScheme: true}).
+		filterLinks(links, "https://monzo.com")
+	if len(result) != 1 || result[0] != "http://instagram.com/monzo" {
+		t.Fatalf("expected: %s, actual: %v", "http://instagram.com/monzo", result)
+	}
+}
+
+func TestParseLinksFromHtmlBodySoftError(t *testing.T) {
+	body := `<html><head><title>Page Not Found</title></head><body><a href="/home">home</a></body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{SoftErrorMarkers: []string{"Page Not Found"}})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if doc.softError != "Page Not Found" {
+		t.Fatalf("expected: %s, actual: %s", "Page Not Found", doc.softError)
+	}
+
+	if len(doc.links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(doc.links))
+	}
+}
+
+func TestParseLinksFromHtmlBodyNoSoftError(t *testing.T) {
+	body := `<html><head><title>About us</title></head><body><a href="/home">home</a></body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{SoftErrorMarkers: []string{"Page Not Found"}})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if doc.softError != "" {
+		t.Fatalf("expected no soft error, actual: %s", doc.softError)
+	}
+}
+
+func TestParseLinksFromHtmlBodyPagination(t *testing.T) {
+	body := `<html><head>
+		<link rel="prev" href="/blog?page=1">
+		<link rel="next" href="/blog?page=3" />
+		<link rel="stylesheet" href="/style.css">
+	</head><body><a href="/home">home</a></body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{FollowPagination: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	expected := []string{"/blog?page=1", "/blog?page=3", "/home"}
+	if strings.Join(doc.links, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected: %v, actual: %v", expected, doc.links)
+	}
+
+	if len(doc.pagination) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(doc.pagination))
+	}
+
+	doc, err = parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{FollowPagination: false})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if len(doc.links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(doc.links))
+	}
+}
+
+func TestParseLinksFromHtmlBodyHeadOnly(t *testing.T) {
+	body := `<html><head>
+		<link rel="next" href="/blog?page=2">
+	</head><body><a href="/home">home</a></body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{HeadOnly: true, FollowPagination: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if strings.Join(doc.links, ",") != "/blog?page=2" {
+		t.Fatalf("expected: %v, actual: %v", []string{"/blog?page=2"}, doc.links)
+	}
+
+	doc, err = parseLinksFromHtmlBody(strings.NewReader(`<html><body><a href="/home">home</a></body></html>`), ParserOptions{HeadOnly: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if len(doc.links) != 0 {
+		t.Fatalf("expected len: %d, actual len: %d", 0, len(doc.links))
+	}
+}
+
+func TestParseLinksFromHtmlBodyHeadOnlyLinkRels(t *testing.T) {
+	body := `<html><head>
+		<link rel="canonical" href="/home">
+		<link rel="alternate" hreflang="fr" href="/fr">
+		<link rel="prev" href="/blog?page=1">
+		<link rel="stylesheet" href="/style.css">
+		<link rel="icon" href="/favicon.ico">
+	</head><body><a href="/about">about</a></body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{HeadOnly: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	expected := []string{"/home", "/fr", "/blog?page=1", "/style.css"}
+	if !slices.Equal(doc.links, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, doc.links)
+	}
+
+	if len(doc.pagination) != 0 {
+		t.Fatalf("expected len: %d, actual len: %d", 0, len(doc.pagination))
+	}
+}
+
+func TestParseLinksFromHtmlBodyNofollow(t *testing.T) {
+	body := `<a href="/a">a</a>
+		<a rel="nofollow" href="/b">b</a>
+		<a rel="NoFollow noopener" href="/c">c</a>
+		<a rel="noopener" href="/d">d</a>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{RespectNofollow: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	expected := []string{"/a", "/d"}
+	if !slices.Equal(doc.links, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, doc.links)
+	}
+
+	doc, err = parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if len(doc.links) != 4 {
+		t.Fatalf("expected len: %d, actual len: %d", 4, len(doc.links))
+	}
+}
+
+func TestParseLinksFromHtmlBodyInlineJSLinks(t *testing.T) {
+	body := `<html><body>
+		<a href="/home">home</a>
+		<div onclick="location.href='/js-nav'">nav</div>
+		<button onmousedown="window.open(&quot;https://monzo.com/js-open&quot;)">open</button>
+		<span onclick="doSomething(1, 'not a url')">nothing</span>
+	</body></html>`
+
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{ParseInlineJSLinks: true})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	expected := []string{"/home", "/js-nav", "https://monzo.com/js-open"}
+	if strings.Join(doc.links, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected: %v, actual: %v", expected, doc.links)
+	}
+
+	doc, err = parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{ParseInlineJSLinks: false})
+	if err != nil {
+		t.Fatal("unexpected error")
+	}
+
+	if len(doc.links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(doc.links))
+	}
+}
+
+func TestHandleRequestMaxInFlightRequests(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 50)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second * 5, MaxInFlightRequests: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := parser.ParseLinks(server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Fatalf("expected at most %d in-flight requests, actual: %d", 2, peak.Load())
+	}
+}
+
+func TestParseLinksMaxInFlightRequestsCoversBody(t *testing.T) {
+	var active, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			p := peak.Load()
+			if current <= p || peak.CompareAndSwap(p, current) {
+				break
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(time.Millisecond * 50)
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second * 5, MaxInFlightRequests: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := parser.ParseLinks(server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 1 {
+		t.Fatalf("expected at most %d in-flight requests, actual: %d", 1, peak.Load())
+	}
+
+	if len(parser.inFlight) != 0 {
+		t.Fatalf("expected released slots: %d, actual held: %d", 0, len(parser.inFlight))
+	}
+}
+
+func TestHandleRequestMaxInFlightRequestsRespectsContext(t *testing.T) {
+	parser := getTestParser(ParserOptions{MaxInFlightRequests: 1})
+	parser.inFlight <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	u, _ := url.Parse("http://127.0.0.1")
+	if _, err := parser.handleRequest(ctx, *u); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseLinksContentHash(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/other" {
+			w.Write([]byte(`<a href="/other">other</a>`))
+			return
+		}
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HashContent: true})
+	first, err := parser.ParseLinks(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := parser.ParseLinks(server.URL + "/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := parser.ParseLinks(server.URL + "/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.ContentHash) <= 0 || first.ContentHash != second.ContentHash {
+		t.Fatalf("expected identical hashes, actual: %s, %s", first.ContentHash, second.ContentHash)
+	}
+
+	if first.ContentHash == other.ContentHash {
+		t.Fatal("expected different hashes for different content")
+	}
+
+	if first.BodySize != int64(len(`<a href="/about">about</a>`)) {
+		t.Fatalf("expected: %d, actual: %d", len(`<a href="/about">about</a>`), first.BodySize)
+	}
+}
+
+func TestParseLinksContentHashCoversWholeBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><head><title>shared</title></head><body>%s</body></html>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HashContent: true, HeadOnly: true})
+	first, err := parser.ParseLinks(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := parser.ParseLinks(server.URL + "/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ContentHash == second.ContentHash {
+		t.Fatal("expected different hashes for pages sharing a head")
+	}
+
+	unhashed, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unhashed.ContentHash) > 0 {
+		t.Fatalf("expected no hash, actual: %s", unhashed.ContentHash)
+	}
+}
+
+func TestFilterLinksSameSubdomainHostPrefix(t *testing.T) {
+	links := []string{
+		"https://monzo.com.evil.com/about",
+		"https://monzocom/about",
+		"https://monzo.company/about",
+		"https://monzo.com/about",
+	}
+
+	result := getTestParser(ParserOptions{SameSubdomain: true}).filterLinks(links, "https://monzo.com")
+	if len(result) != 1 || result[0] != "https://monzo.com/about" {
+		t.Fatalf("expected: %v, actual: %v", []string{"https://monzo.com/about"}, result)
+	}
+}
+
+func TestFilterLinksProtocolRelative(t *testing.T) {
+	links := []string{
+		"//monzo.com/about",
+		"//instagram.com/monzo",
+	}
+
+	result := getTestParser(ParserOptions{SameSubdomain: true}).filterLinks(links, "https://monzo.com")
+	if len(result) != 1 || result[0] != "https://monzo.com/about" {
+		t.Fatalf("expected: %v, actual: %v", []string{"https://monzo.com/about"}, result)
+	}
+}
+
+func TestFilterLinksMalformed(t *testing.T) {
+	links := []string{
+		"",
+		"   ",
+		"mailto:help@monzo.com",
+		"javascript:void(0)",
+		"https://",
+		"https://:80/about",
+		"https://mon zo.com/about",
+		"  /about  ",
+	}
+
+	result := getTestParser(ParserOptions{}).filterLinks(links, "https://monzo.com")
+	if len(result) != 1 || result[0] != "https://monzo.com/about" {
+		t.Fatalf("expected: %v, actual: %v", []string{"https://monzo.com/about"}, result)
+	}
+}
+
+func TestFilterLinksResolvesPathRelativeLinks(t *testing.T) {
+	links := []string{
+		"about",
+		"../careers",
+		"./comments",
+		"/help",
+		"//monzo.com/blog",
+	}
+
+	result := getTestParser(ParserOptions{}).filterLinks(links, "https://monzo.com/blog/post/")
+	expected := []string{
+		"https://monzo.com/blog/post/about",
+		"https://monzo.com/blog/careers",
+		"https://monzo.com/blog/post/comments",
+		"https://monzo.com/help",
+		"https://monzo.com/blog",
+	}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestFilterLinksResolvesAgainstPageFile(t *testing.T) {
+	links := []string{
+		"page.html",
+		"../sibling",
+		"./child",
+	}
+
+	result := getTestParser(ParserOptions{SameSubdomain: true}).filterLinks(links, "https://monzo.com/blog/post/index.html")
+	expected := []string{
+		"https://monzo.com/blog/post/page.html",
+		"https://monzo.com/blog/sibling",
+		"https://monzo.com/blog/post/child",
+	}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestParseLinksResolvesAgainstPageUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="comments">comments</a>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL + "/blog/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.Links) != 1 || output.Links[0] != server.URL+"/blog/comments" {
+		t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/blog/comments"}, output.Links)
+	}
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer server.Close()
+
+	for _, insecure := range []bool{false, true} {
+		p, err := NewParser(ParserOptions{Timeout: time.Second, InsecureSkipVerify: insecure})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := p.ParseLinks(server.URL)
+		if (err == nil) != insecure {
+			t.Fatalf("expected success: %v, actual error: %v", insecure, err)
+		}
+
+		if insecure && len(output.Links) != 1 {
+			t.Fatalf("expected len: %d, actual len: %d", 1, len(output.Links))
+		}
+	}
+}
+
+func TestProxyURL(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer proxy.Close()
+
+	p, err := NewParser(ParserOptions{Timeout: time.Second, ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := p.ParseLinks("http://monzo.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proxied.Load() != "http://monzo.example/" {
+		t.Fatalf("expected: %s, actual: %v", "http://monzo.example/", proxied.Load())
+	}
+
+	if len(output.Links) != 1 || output.Links[0] != "http://monzo.example/about" {
+		t.Fatalf("expected: %v, actual: %v", []string{"http://monzo.example/about"}, output.Links)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p, err := NewParser(ParserOptions{Timeout: 5 * time.Second, ResponseHeaderTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = p.ParseLinks(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Fatalf("expected a response header timeout, actual: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the header timeout to fire before the deadline, actual: %s", elapsed)
+	}
+}
+
+func TestInvalidProxyURL(t *testing.T) {
+	for _, proxy := range []string{"ftp://proxy.example", "socks5://", "://proxy"} {
+		if _, err := NewParser(ParserOptions{ProxyURL: proxy}); err == nil {
+			t.Fatalf("expected error for proxy %s", proxy)
+		}
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	var external atomic.Bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, ok := r.BasicAuth()
+		external.Store(ok)
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "staging" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path == "/external" {
+			http.Redirect(w, r, other.URL, http.StatusFound)
+			return
+		}
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected status: %d, actual status: %d", http.StatusUnauthorized, output.StatusCode)
+	}
+
+	p := getTestParser(ParserOptions{Timeout: time.Second, BasicAuthUser: "staging", BasicAuthPass: "secret", BasicAuthHosts: []string{host}})
+	output, err = p.ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusOK || len(output.Links) != 1 {
+		t.Fatalf("expected: %d %d, actual: %d %d", http.StatusOK, 1, output.StatusCode, len(output.Links))
+	}
+
+	if _, err := p.ParseLinks(server.URL + "/external"); err != nil {
+		t.Fatal(err)
+	}
+
+	if external.Load() {
+		t.Fatal("expected credentials not to be sent to a redirected host")
+	}
+}
+
+func TestParseLinksMaxBodyBytes(t *testing.T) {
+	page := `<a href="/first">first</a>` + strings.Repeat(" ", 1000) + `<a href="/second">second</a>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, MaxBodyBytes: 100}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(output.Links, []string{server.URL + "/first"}) {
+		t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/first"}, output.Links)
+	}
+
+	if output.BodySize != 100 {
+		t.Fatalf("expected: %d, actual: %d", 100, output.BodySize)
+	}
+}
+
+func TestParseLinksMaxBodyBytesWithHashContent(t *testing.T) {
+	page := `<a href="/first">first</a>` + strings.Repeat(" ", 1000) + `<a href="/second">second</a>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, MaxBodyBytes: 100, HashContent: true}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(output.Links, []string{server.URL + "/first"}) {
+		t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/first"}, output.Links)
+	}
+
+	if output.BodySize != 100 {
+		t.Fatalf("expected: %d, actual: %d", 100, output.BodySize)
+	}
+
+	expected := sha256.Sum256([]byte(page[:100]))
+	if output.ContentHash != hex.EncodeToString(expected[:]) {
+		t.Fatalf("expected: %s, actual: %s", hex.EncodeToString(expected[:]), output.ContentHash)
+	}
+}
+
+func TestTruncatingReader(t *testing.T) {
+	for _, c := range []struct {
+		body      string
+		truncated bool
+	}{{"abcd", false}, {"abcde", true}} {
+		reader := &truncatingReader{reader: strings.NewReader(c.body), remaining: 4}
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != "abcd" || reader.truncated != c.truncated {
+			t.Fatalf("expected: %s %v, actual: %s %v", "abcd", c.truncated, b, reader.truncated)
+		}
+	}
+}
+
+func TestParseLinksSkipsUnparseableContentTypes(t *testing.T) {
+	contentType := "application/json"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(`{"html": "<a href=\"/about\">about</a>"}`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.Links) != 0 || output.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d %d, actual: %d %d", 0, http.StatusOK, len(output.Links), output.StatusCode)
+	}
+
+	output, err = getTestParser(ParserOptions{Timeout: time.Second, ParseableContentTypes: []string{"application/json"}}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.Links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(output.Links))
+	}
+
+	contentType = "application/xhtml+xml; charset=utf-8"
+	output, err = getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(output.Links) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(output.Links))
+	}
+}
+
+func TestParseLinksFollowMetaRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta http-equiv="Refresh" content="0; URL='/next'"></head><body><a href="/about">about</a></body></html>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(output.Links, []string{server.URL + "/about"}) {
+		t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/about"}, output.Links)
+	}
+
+	output, err = getTestParser(ParserOptions{Timeout: time.Second, FollowMetaRefresh: true}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{server.URL + "/next", server.URL + "/about"}
+	if !slices.Equal(output.Links, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, output.Links)
+	}
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	for content, expected := range map[string]string{
+		"0;url=/next":                    "/next",
+		"5; URL = \"https://monzo.com\"": "https://monzo.com",
+		"0,url=page.html":                "page.html",
+		"30":                             "",
+		"0; /next":                       "",
+	} {
+		target, _ := parseMetaRefresh([]html.Attribute{{Key: "http-equiv", Val: "refresh"}, {Key: "content", Val: content}})
+		if target != expected {
+			t.Fatalf("expected: %s, actual: %s", expected, target)
+		}
+	}
+}
+
+func TestParseLinksResolvesAgainstBaseHref(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><base href="/docs/v2/"><base href="/ignored/"></head>
+<body><a href="guide">guide</a><a href="../v1/intro">intro</a><a href="/help">help</a><a href="https://monzo.com/about">about</a></body></html>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL + "/blog/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{server.URL + "/docs/v2/guide", server.URL + "/docs/v1/intro", server.URL + "/help", "https://monzo.com/about"}
+	if !slices.Equal(output.Links, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, output.Links)
+	}
+}
+
+func TestParseLinksCanonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><base href="/docs/"><link rel="canonical" href="guide/"><link rel="canonical" href="/ignored"></head></html>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, SameSubdomain: true}).ParseLinks(server.URL + "/print/guide")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Canonical != server.URL+"/docs/guide" {
+		t.Fatalf("expected: %s, actual: %s", server.URL+"/docs/guide", output.Canonical)
+	}
+}
+
+func TestParseLinksTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>
+    Fish &amp; Chips &#8211; Menu
+</title></head><body><svg><title>icon</title></svg></body></html>`))
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Title != "Fish & Chips – Menu" {
+		t.Fatalf("expected: %s, actual: %s", "Fish & Chips – Menu", output.Title)
+	}
+}
+
+func TestParseLinksFromHtmlBodyMissingTitle(t *testing.T) {
+	for _, body := range []string{`<a href="/a">a</a>`, `<title>   </title>`, `<title>unterminated`} {
+		doc, err := parseLinksFromHtmlBody(strings.NewReader(body), ParserOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(doc.title) > 0 {
+			t.Fatalf("expected an empty title, actual: %q for %s", doc.title, body)
+		}
+	}
+}
+
+func TestParseLinksFromHtmlBodyBase(t *testing.T) {
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(`<base href=" https://monzo.com/blog/ "><a href="post">post</a>`), ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.base != "https://monzo.com/blog/" {
+		t.Fatalf("expected: %s, actual: %s", "https://monzo.com/blog/", doc.base)
+	}
+
+	doc.resolveBase("https://monzo.com/about")
+	if !slices.Equal(doc.links, []string{"https://monzo.com/blog/post"}) {
+		t.Fatalf("expected: %v, actual: %v", []string{"https://monzo.com/blog/post"}, doc.links)
+	}
+}
+
+func TestFilterLinksSameSubdomainDefaultPort(t *testing.T) {
+	links := []string{
+		"https://monzo.com:443/about",
+		"https://MONZO.com/blog",
+		"https://monzo.com:8443/help",
+		"http://monzo.com:443/careers",
+	}
+
+	result := getTestParser(ParserOptions{SameSubdomain: true}).filterLinks(links, "https://monzo.com")
+	expected := []string{"https://monzo.com:443/about", "https://MONZO.com/blog"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestFilterLinksRelativeWithoutBase(t *testing.T) {
+	_, reason := getDefaultTestParser().filterLink("/about", "")
+	if len(reason) <= 0 {
+		t.Fatal("expected link to be skipped")
+	}
+}
+
+func TestHandleRequestAcceptHeader(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+	}))
+	defer server.Close()
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if accept != DefaultAccept {
+		t.Fatalf("expected: %s, actual: %s", DefaultAccept, accept)
+	}
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second, Accept: "application/xml"}).ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if accept != "application/xml" {
+		t.Fatalf("expected: %s, actual: %s", "application/xml", accept)
+	}
+}
+
+func TestHandleRequestUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer server.Close()
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second}).ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if userAgent != DefaultUserAgent {
+		t.Fatalf("expected: %s, actual: %s", DefaultUserAgent, userAgent)
+	}
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second, UserAgent: "audit-bot/2.0"}).ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if userAgent != "audit-bot/2.0" {
+		t.Fatalf("expected: %s, actual: %s", "audit-bot/2.0", userAgent)
+	}
+}
+
+func TestHandleRequestRateLimitPerHost(t *testing.T) {
+	var received []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, time.Now())
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, RequestsPerSecond: 10})
+	for i := 0; i < 2; i++ {
+		if _, err := parser.ParseLinks(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if gap := received[1].Sub(received[0]); gap < 80*time.Millisecond {
+		t.Fatalf("expected a gap of at least: %s, actual: %s", 80*time.Millisecond, gap)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := parser.waitForHost(ctx, strings.TrimPrefix(server.URL, "http://")); err == nil {
+		t.Fatal("expected a cancelled context to stop waiting")
+	}
+}
+
+func TestHandleRequestRetriesWithBackoff(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: time.Second, MaxRetries: 2, RetryBackoff: 10 * time.Millisecond}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusOK || attempts.Load() != 3 {
+		t.Fatalf("expected: %d after %d attempts, actual: %d after %d attempts", http.StatusOK, 3, output.StatusCode, attempts.Load())
+	}
+
+	attempts.Store(0)
+	output, err = getTestParser(ParserOptions{Timeout: time.Second, MaxRetries: 1, RetryBackoff: 10 * time.Millisecond}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusServiceUnavailable || attempts.Load() != 2 {
+		t.Fatalf("expected: %d after %d attempts, actual: %d after %d attempts", http.StatusServiceUnavailable, 2, output.StatusCode, attempts.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		header := http.Header{}
+		header.Set("Retry-After", test.value)
+		delay, ok := parseRetryAfter(header, now)
+		if ok != test.ok || delay != test.expected {
+			t.Fatalf("expected: %s %t, actual: %s %t for [%s]", test.expected, test.ok, delay, ok, test.value)
+		}
+	}
+}
+
+func TestHandleRequestRetryAfter(t *testing.T) {
+	var received []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, time.Now())
+		if len(received) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	output, err := getTestParser(ParserOptions{Timeout: 3 * time.Second, MaxRetries: 1, RetryBackoff: time.Millisecond}).ParseLinks(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d", http.StatusOK, output.StatusCode)
+	}
+
+	if gap := received[1].Sub(received[0]); gap < 900*time.Millisecond {
+		t.Fatalf("expected a gap of at least: %s, actual: %s", 900*time.Millisecond, gap)
+	}
+}
+
+func TestFetchFollowsRedirectChains(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/older/", http.StatusMovedPermanently)
+		case "/older/":
+			w.Header().Set("Location", "../new")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		case "/new":
+			fmt.Fprint(w, `<a href="/about">about</a>`)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second})
+	output, err := parser.ParseLinks(server.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.StatusCode != http.StatusOK || strings.Join(output.Links, ",") != server.URL+"/about" {
+		t.Fatalf("expected: %d %s, actual: %d %v", http.StatusOK, server.URL+"/about", output.StatusCode, output.Links)
+	}
+
+	u, _ := url.Parse(server.URL + "/old")
+	res, err := parser.get(context.Background(), *u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeBody(res.Body)
+
+	expected := []string{server.URL + "/old", server.URL + "/older/"}
+	if !slices.Equal(res.RedirectChain, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, res.RedirectChain)
+	}
+
+	if _, err := parser.ParseLinks(server.URL + "/loop"); err == nil || !strings.Contains(err.Error(), "redirect loop") {
+		t.Fatalf("expected a redirect loop error, actual: %v", err)
+	}
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second, MaxRedirects: 1}).ParseLinks(server.URL + "/old"); err == nil {
+		t.Fatalf("expected an error after %d redirects", 1)
+	}
+}
+
+func TestHandleRequestHeadersPerHost(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	parser := getTestParser(ParserOptions{
+		Timeout: time.Second,
+		Headers: map[string]string{"Authorization": "Bearer global", "X-Team": "crawler"},
+		HeadersPerHost: map[string]map[string]string{
+			host:            {"Authorization": "Bearer local"},
+			"api.monzo.com": {"Authorization": "Bearer api"},
+		},
+	})
+
+	if _, err := parser.ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Get("Authorization") != "Bearer local" {
+		t.Fatalf("expected: %s, actual: %s", "Bearer local", received.Get("Authorization"))
+	}
+
+	if received.Get("X-Team") != "crawler" {
+		t.Fatalf("expected: %s, actual: %s", "crawler", received.Get("X-Team"))
+	}
+}
+
+func TestHandleRequestHeadersIgnoreManaged(t *testing.T) {
+	var received http.Header
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		host = r.Host
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{
+		Timeout: time.Second,
+		Headers: map[string]string{"host": "evil.example.com", "Connection": "upgrade", "Accept-Language": "en-GB", "Referer": "https://monzo.com"},
+	})
+
+	if _, err := parser.ParseLinks(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if host != strings.TrimPrefix(server.URL, "http://") {
+		t.Fatalf("expected: %s, actual: %s", strings.TrimPrefix(server.URL, "http://"), host)
+	}
+
+	if received.Get("Connection") == "upgrade" {
+		t.Fatal("expected the connection header not to be overridden")
+	}
+
+	if received.Get("Accept-Language") != "en-GB" || received.Get("Referer") != "https://monzo.com" {
+		t.Fatalf("expected: %s %s, actual: %s %s", "en-GB", "https://monzo.com", received.Get("Accept-Language"), received.Get("Referer"))
+	}
+}
+
+func TestFilterLinksHostAliases(t *testing.T) {
+	links := []string{
+		"https://m.monzo.com/about",
+		"https://monzo.com/about",
+		"https://M.monzo.com:8443/blog",
+		"https://instagram.com/monzo",
+	}
+
+	result := getTestParser(ParserOptions{
+		Distinct:    true,
+		HostAliases: map[string]string{"m.monzo.com": "monzo.com"},
+	}).filterLinks(links, "https://monzo.com")
+	slices.Sort(result)
+
+	expected := []string{"https://instagram.com/monzo", "https://monzo.com/about", "https://monzo.com:8443/blog"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestFilterLinksAllowedDomains(t *testing.T) {
+	links := []string{
+		"https://www.example.com/about",
+		"https://blog.example.com/post",
+		"https://example.com/",
+		"https://notexample.com/",
+		"https://example.org/",
+	}
+
+	result := getTestParser(ParserOptions{SameSubdomain: true, AllowedDomains: []string{"example.com"}}).
+		filterLinks(links, "https://www.example.com")
+	slices.Sort(result)
+
+	expected := []string{"https://blog.example.com/post", "https://example.com", "https://www.example.com/about"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+
+	result = getTestParser(ParserOptions{SameSubdomain: true}).filterLinks(links, "https://www.example.com")
+	if len(result) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(result))
+	}
+}
+
+func TestFilterLinksHostAliasesSameSubdomain(t *testing.T) {
+	links := []string{
+		"https://monzo.com/about",
+		"https://m.monzo.com/blog",
+	}
+
+	result := getTestParser(ParserOptions{
+		SameSubdomain: true,
+		HostAliases:   map[string]string{"m.monzo.com": "monzo.com"},
+	}).filterLinks(links, "https://m.monzo.com")
+	if len(result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(result))
+	}
+}
+
+func getDefaultTestParser() *Parser {
+	return getTestParser(ParserOptions{})
+}
+
+func getTestParser(opts ParserOptions) *Parser {
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+
+	parser, err := NewParser(opts)
+	if err != nil {
+		panic(err)
+	}
+	return parser
+}
+
+func TestFilterAssets(t *testing.T) {
+	body := `<link rel="stylesheet" href="/style.css"><link rel="canonical" href="/home"><img src="logo.png"><a href="/report.pdf">report</a><a href="/about">about</a>`
+	opts := ParserOptions{CollectAssets: true, IgnoredExtensions: []string{".pdf"}}
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := getTestParser(opts).filterAssets(doc.links, doc.assets, "https://monzo.com/blog/")
+	slices.Sort(result)
+
+	expected := []string{"https://monzo.com/blog/logo.png", "https://monzo.com/report.pdf", "https://monzo.com/style.css"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
+
+func TestFilterCSSAssets(t *testing.T) {
+	body := `<html><head><style>
+		body { background: url("/img/bg.png"); }
+		@font-face { src: url(fonts/brand.woff2) format("woff2"), url('data:font/woff;base64,AAAA'); }
+	</style></head><body><div style="background-image: url('/img/hero.jpg')">hero</div><p>url(/not-css.png)</p></body></html>`
+	opts := ParserOptions{ParseCSSUrls: true}
+	doc, err := parseLinksFromHtmlBody(strings.NewReader(body), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := getTestParser(opts).filterCSSAssets(doc.cssAssets, "https://monzo.com/blog/")
+	slices.Sort(result)
+
+	expected := []string{"https://monzo.com/blog/fonts/brand.woff2", "https://monzo.com/img/bg.png", "https://monzo.com/img/hero.jpg"}
+	if !slices.Equal(result, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
 }
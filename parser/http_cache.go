@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type httpCache struct {
+	dir string
+}
+
+type httpCacheEntry struct {
+	URL           string      `json:"url"`
+	FinalURL      string      `json:"finalUrl,omitempty"`
+	RedirectChain []string    `json:"redirectChain,omitempty"`
+	Status        string      `json:"status"`
+	StatusCode    int         `json:"statusCode"`
+	Header        http.Header `json:"header"`
+	Body          []byte      `json:"body"`
+	Expires       time.Time   `json:"expires"`
+}
+
+func newHttpCache(dir string) *httpCache {
+	return &httpCache{dir: dir}
+}
+
+func (c *httpCache) Get(url string) (CachedResponse, bool) {
+	b, err := os.ReadFile(c.path(url))
+	if err != nil {
+		return CachedResponse{}, false
+	}
+
+	var entry httpCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		hclog.Default().Warn("ignoring corrupt http cache entry", "url", url, "error", err)
+		return CachedResponse{}, false
+	}
+
+	if entry.URL != url {
+		return CachedResponse{}, false
+	}
+
+	finalUrl := entry.FinalURL
+	if len(finalUrl) <= 0 {
+		finalUrl = entry.URL
+	}
+
+	return CachedResponse{
+		URL:           finalUrl,
+		RedirectChain: entry.RedirectChain,
+		Status:        entry.Status,
+		StatusCode:    entry.StatusCode,
+		Header:        entry.Header,
+		Body:          entry.Body,
+		Expires:       entry.Expires,
+	}, true
+}
+
+func (c *httpCache) Set(url string, response CachedResponse) {
+	b, err := json.Marshal(httpCacheEntry{
+		URL:           url,
+		FinalURL:      response.URL,
+		RedirectChain: response.RedirectChain,
+		Status:        response.Status,
+		StatusCode:    response.StatusCode,
+		Header:        response.Header,
+		Body:          response.Body,
+		Expires:       response.Expires,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		hclog.Default().Warn("failed to create http cache dir", "dir", c.dir, "error", err)
+		return
+	}
+
+	if err := WriteFileAtomic(c.path(url), b); err != nil {
+		hclog.Default().Warn("failed to write http cache entry", "url", url, "error", err)
+	}
+}
+
+func (c *httpCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func freshUntil(header http.Header, now time.Time) (time.Time, bool) {
+	cacheControl := header.Get("Cache-Control")
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-store" || directive == "no-cache" {
+			return time.Time{}, false
+		}
+	}
+
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || seconds <= 0 {
+			return time.Time{}, false
+		}
+
+		return now.Add(time.Second * time.Duration(seconds)), true
+	}
+
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil || !expires.After(now) {
+		return time.Time{}, false
+	}
+
+	return expires, true
+}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHttpCacheSkipsFreshResources(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HTTPCacheDir: t.TempDir()})
+	for i := 0; i < 2; i++ {
+		output, err := parser.ParseLinks(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(output.Links) != 1 {
+			t.Fatalf("expected len: %d, actual len: %d", 1, len(output.Links))
+		}
+	}
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected hits: %d, actual hits: %d", 1, hits.Load())
+	}
+}
+
+func TestHttpCacheKeepsRedirectChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HTTPCacheDir: t.TempDir()})
+	input, err := url.Parse(server.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := parser.get(context.Background(), *input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Cached != (i == 1) {
+			t.Fatalf("expected cached: %t, actual: %t", i == 1, res.Cached)
+		}
+
+		if res.URL != server.URL+"/new" {
+			t.Fatalf("expected: %s, actual: %s", server.URL+"/new", res.URL)
+		}
+
+		if len(res.RedirectChain) != 1 || res.RedirectChain[0] != server.URL+"/old" {
+			t.Fatalf("expected: %v, actual: %v", []string{server.URL + "/old"}, res.RedirectChain)
+		}
+	}
+}
+
+func TestHttpCacheRefetchesUncacheableResources(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HTTPCacheDir: t.TempDir()})
+	for i := 0; i < 2; i++ {
+		if _, err := parser.ParseLinks(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if hits.Load() != 2 {
+		t.Fatalf("expected hits: %d, actual hits: %d", 2, hits.Load())
+	}
+}
+
+func TestHttpCacheRevalidatesStaleEntries(t *testing.T) {
+	var hits, revalidated atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HTTPCacheDir: t.TempDir()})
+	input, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, c := range []struct {
+		at          time.Duration
+		hits        int32
+		revalidated int32
+	}{{0, 1, 0}, {30 * time.Second, 1, 0}, {2 * time.Minute, 2, 1}, {150 * time.Second, 2, 1}} {
+		res, err := parser.cached(context.Background(), *input, now.Add(c.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(body) != `<a href="/about">about</a>` || res.StatusCode != http.StatusOK {
+			t.Fatalf("expected: %d %s, actual: %d %s", http.StatusOK, `<a href="/about">about</a>`, res.StatusCode, body)
+		}
+
+		if hits.Load() != c.hits || revalidated.Load() != c.revalidated {
+			t.Fatalf("expected hits: %d %d, actual hits: %d %d at %s", c.hits, c.revalidated, hits.Load(), revalidated.Load(), c.at)
+		}
+	}
+}
+
+func TestFreshUntilMaxAge(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=30")
+
+	expires, ok := freshUntil(header, now)
+	if !ok {
+		t.Fatal("expected resource to be cacheable")
+	}
+
+	if !expires.Equal(now.Add(time.Second * 30)) {
+		t.Fatalf("expected: %s, actual: %s", now.Add(time.Second*30), expires)
+	}
+}
+
+func TestFreshUntilExpires(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("Expires", now.Add(time.Hour).UTC().Format(http.TimeFormat))
+	if _, ok := freshUntil(header, now); !ok {
+		t.Fatal("expected resource to be cacheable")
+	}
+
+	header.Set("Expires", now.Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if _, ok := freshUntil(header, now); ok {
+		t.Fatal("expected expired resource to be uncacheable")
+	}
+}
+
+func TestFreshUntilNoCache(t *testing.T) {
+	header := http.Header{}
+	header.Set("Cache-Control", "no-cache, max-age=30")
+	if _, ok := freshUntil(header, time.Now()); ok {
+		t.Fatal("expected resource to be uncacheable")
+	}
+
+	if _, ok := freshUntil(http.Header{}, time.Now()); ok {
+		t.Fatal("expected resource without freshness headers to be uncacheable")
+	}
+}
+
+func TestHttpCacheHitsAreNotDownloaded(t *testing.T) {
+	body := `<a href="/about">about</a>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, HTTPCacheDir: t.TempDir()})
+	for i := 0; i < 2; i++ {
+		if _, err := parser.ParseLinks(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if parser.DownloadedBytes() != int64(len(body)) {
+		t.Fatalf("expected: %d, actual: %d", len(body), parser.DownloadedBytes())
+	}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var DefaultStripQueryParams = []string{"utm_*", "fbclid", "gclid"}
+
+func SanitiseUrlWithQuery(rawUrl string, stripParams []string) (string, error) {
+	sanitised, err := SanitiseUrl(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsedUrl.Query()
+	for name := range query {
+		if matchesParam(name, stripParams) {
+			query.Del(name)
+		}
+	}
+
+	if len(query) <= 0 {
+		return sanitised, nil
+	}
+	return fmt.Sprintf("%s?%s", sanitised, query.Encode()), nil
+}
+
+func (p *Parser) NormalizeUrl(rawUrl string) (string, error) {
+	if !p.opts.NormalizeQuery {
+		return SanitiseUrl(rawUrl)
+	}
+	return SanitiseUrlWithQuery(rawUrl, p.opts.StripQueryParams)
+}
+
+func normalizedHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	if len(port) > 0 {
+		return net.JoinHostPort(host, port)
+	}
+
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+func matchesParam(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(name, prefix) {
+			return true
+		}
+
+		if name == pattern {
+			return true
+		}
+	}
+	return false
+}
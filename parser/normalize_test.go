@@ -0,0 +1,69 @@
+package parser
+
+import "testing"
+
+func TestSanitiseUrlNormalizesHost(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"HTTPS://Monzo.COM/About/", "https://monzo.com/About"},
+		{"https://monzo.com:443/blog", "https://monzo.com/blog"},
+		{"http://monzo.com:80/blog", "http://monzo.com/blog"},
+		{"http://monzo.com:443/blog", "http://monzo.com:443/blog"},
+		{"https://monzo.com:8443/blog", "https://monzo.com:8443/blog"},
+		{"https://[::1]:443/blog", "https://[::1]/blog"},
+		{"https://monzo.com/blog?page=2", "https://monzo.com/blog"},
+	}
+
+	for _, test := range tests {
+		actual, err := SanitiseUrl(test.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != test.expected {
+			t.Fatalf("expected: %s, actual: %s", test.expected, actual)
+		}
+	}
+}
+
+func TestSanitiseUrlWithQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://monzo.com/a?b=1&c=2", "https://monzo.com/a?b=1&c=2"},
+		{"https://monzo.com/a?c=2&b=1", "https://monzo.com/a?b=1&c=2"},
+		{"https://Monzo.com:443/a/?c=2&utm_source=x&b=1", "https://monzo.com/a?b=1&c=2"},
+		{"https://monzo.com/a?UTM_Medium=email&gclid=abc", "https://monzo.com/a"},
+		{"https://monzo.com/a?q=hello+world&q=again", "https://monzo.com/a?q=hello+world&q=again"},
+		{"https://monzo.com/a", "https://monzo.com/a"},
+	}
+
+	for _, test := range tests {
+		actual, err := SanitiseUrlWithQuery(test.input, DefaultStripQueryParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != test.expected {
+			t.Fatalf("expected: %s, actual: %s", test.expected, actual)
+		}
+	}
+}
+
+func TestFilterLinksNormalizeQuery(t *testing.T) {
+	links := []string{"/a?c=2&b=1", "/a?b=1&c=2", "/a?b=1&c=2&utm_campaign=x", "/a?b=2"}
+
+	result := getTestParser(ParserOptions{Distinct: true, NormalizeQuery: true, StripQueryParams: DefaultStripQueryParams}).
+		filterLinks(links, "https://monzo.com")
+	if len(result) != 2 {
+		t.Fatalf("expected len: %d, actual len: %d", 2, len(result))
+	}
+
+	result = getTestParser(ParserOptions{Distinct: true}).filterLinks(links, "https://monzo.com")
+	if len(result) != 1 {
+		t.Fatalf("expected len: %d, actual len: %d", 1, len(result))
+	}
+}
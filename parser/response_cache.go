@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type Cache interface {
+	Get(url string) (CachedResponse, bool)
+	Set(url string, response CachedResponse)
+}
+
+type CachedResponse struct {
+	URL           string
+	RedirectChain []string
+	Status        string
+	StatusCode    int
+	Header        http.Header
+	Body          []byte
+	Expires       time.Time
+}
+
+func (c CachedResponse) response(latency time.Duration) SimpleHttpResponse {
+	return SimpleHttpResponse{
+		Body:          bytes.NewReader(c.Body),
+		Status:        c.Status,
+		StatusCode:    c.StatusCode,
+		Header:        c.Header,
+		URL:           c.URL,
+		RedirectChain: c.RedirectChain,
+		ContentLength: int64(len(c.Body)),
+		Latency:       latency,
+		Cached:        true,
+	}
+}
+
+type MemoryCache struct {
+	entries map[string]CachedResponse
+	lock    sync.RWMutex
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]CachedResponse)}
+}
+
+func (c *MemoryCache) Get(url string) (CachedResponse, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	response, ok := c.entries[url]
+	return response, ok
+}
+
+func (c *MemoryCache) Set(url string, response CachedResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[url] = response
+}
+
+func (p *Parser) cached(ctx context.Context, url url.URL, now time.Time) (SimpleHttpResponse, error) {
+	key := url.String()
+	cached, ok := p.cache.Get(key)
+	if ok && now.Before(cached.Expires) {
+		hclog.Default().Trace("http cache hit", "url", key, "expires", cached.Expires)
+		return cached.response(0), nil
+	}
+
+	var headers map[string]string
+	if ok {
+		headers = conditionalHeaders(cached.Header)
+	}
+
+	res, err := p.fetchWithHeaders(ctx, http.MethodGet, url, headers)
+	if err != nil {
+		return res, err
+	}
+
+	if len(headers) > 0 && res.StatusCode == http.StatusNotModified {
+		closeBody(res.Body)
+		hclog.Default().Trace("http cache revalidated", "url", key)
+		if expires, fresh := freshUntil(res.Header, now); fresh {
+			cached.Expires = expires
+			p.cache.Set(key, cached)
+		}
+		return cached.response(res.Latency), nil
+	}
+
+	return p.store(key, res, now)
+}
+
+func (p *Parser) store(key string, res SimpleHttpResponse, now time.Time) (SimpleHttpResponse, error) {
+	if res.StatusCode != http.StatusOK || noStore(res.Header) {
+		return res, nil
+	}
+
+	expires, fresh := freshUntil(res.Header, now)
+	if !fresh && len(conditionalHeaders(res.Header)) <= 0 {
+		return res, nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	closeBody(res.Body)
+	res.Body = bytes.NewReader(body)
+	if err != nil {
+		return res, err
+	}
+
+	p.cache.Set(key, CachedResponse{
+		URL:           res.URL,
+		RedirectChain: res.RedirectChain,
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		Body:          body,
+		Expires:       expires,
+	})
+	return res, nil
+}
+
+func conditionalHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	if etag := header.Get("ETag"); len(etag) > 0 {
+		headers["If-None-Match"] = etag
+	}
+	if lastModified := header.Get("Last-Modified"); len(lastModified) > 0 {
+		headers["If-Modified-Since"] = lastModified
+	}
+	return headers
+}
+
+func noStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
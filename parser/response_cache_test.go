@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCacheRevalidates(t *testing.T) {
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if match := r.Header.Get("If-None-Match"); len(match) > 0 {
+			conditional = append(conditional, match)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second, SameSubdomain: true, Cache: NewMemoryCache()})
+	first, err := parser.ParseLinks(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second, err := parser.ParseLinks(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !slices.Equal(conditional, []string{`"v1"`}) {
+		t.Fatalf("expected: %v, actual: %v", []string{`"v1"`}, conditional)
+	}
+
+	expected := []string{server.URL + "/about"}
+	if !slices.Equal(first.Links, expected) || !slices.Equal(second.Links, expected) {
+		t.Fatalf("expected: %v, actual: %v %v", expected, first.Links, second.Links)
+	}
+
+	if second.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d", http.StatusOK, second.StatusCode)
+	}
+}
+
+func TestCacheSkipsResponsesWithoutValidators(t *testing.T) {
+	cache := NewMemoryCache()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="/about">about</a>`)
+	}))
+	defer server.Close()
+
+	if _, err := getTestParser(ParserOptions{Timeout: time.Second, Cache: cache}).ParseLinks(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := cache.Get(server.URL); ok {
+		t.Fatalf("expected no cache entry for %s", server.URL)
+	}
+}
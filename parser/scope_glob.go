@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '*' {
+			builder.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			continue
+		}
+
+		if i+1 < len(pattern) && pattern[i+1] == '*' {
+			builder.WriteString(".*")
+			i++
+			continue
+		}
+
+		builder.WriteString("[^/]*")
+	}
+	builder.WriteString("$")
+
+	re, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid scope glob %s: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	var globs []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, re)
+	}
+	return globs, nil
+}
+
+func matchesAnyGlob(link string, globs []*regexp.Regexp) bool {
+	for _, glob := range globs {
+		if glob.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestCompileGlobSingleStar(t *testing.T) {
+	glob, err := compileGlob("https://monzo.com/blog/*/comments")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]bool{
+		"https://monzo.com/blog/post-1/comments":       true,
+		"https://monzo.com/blog//comments":             true,
+		"https://monzo.com/blog/2023/post-1/comments":  false,
+		"https://monzo.com/blog/post-1/comments/reply": false,
+		"https://monzo.com/blog/post-1":                false,
+	}
+
+	for link, expected := range cases {
+		if glob.MatchString(link) != expected {
+			t.Fatalf("expected: %t, actual: %t for %s", expected, !expected, link)
+		}
+	}
+}
+
+func TestCompileGlobDoubleStar(t *testing.T) {
+	glob, err := compileGlob("https://monzo.com/blog/**")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]bool{
+		"https://monzo.com/blog/post-1":               true,
+		"https://monzo.com/blog/2023/post-1/comments": true,
+		"https://monzo.com/about":                     false,
+		"https://monzo.com/blog":                      false,
+	}
+
+	for link, expected := range cases {
+		if glob.MatchString(link) != expected {
+			t.Fatalf("expected: %t, actual: %t for %s", expected, !expected, link)
+		}
+	}
+}
+
+func TestCompileGlobEscapesMeta(t *testing.T) {
+	glob, err := compileGlob("https://monzo.com/search?q=*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !glob.MatchString("https://monzo.com/search?q=cards") {
+		t.Fatal("expected literal ? to match")
+	}
+
+	if glob.MatchString("https://monzo.com/searchXq=cards") {
+		t.Fatal("expected ? not to act as a wildcard")
+	}
+}
+
+func TestFilterLinksScopeGlobs(t *testing.T) {
+	links := []string{
+		"/blog/post-1/comments",
+		"/blog/post-1",
+		"/help/cards/lost",
+		"/about",
+	}
+
+	result := getTestParser(ParserOptions{
+		ScopeGlobs: []string{"https://monzo.com/blog/*/comments", "https://monzo.com/help/**"},
+	}).filterLinks(links, "https://monzo.com")
+
+	expected := []string{"https://monzo.com/blog/post-1/comments", "https://monzo.com/help/cards/lost"}
+	if len(result) != len(expected) || result[0] != expected[0] || result[1] != expected[1] {
+		t.Fatalf("expected: %v, actual: %v", expected, result)
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type sitemapDocument struct {
+	Urls     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+func (p *Parser) SitemapUrls(ctx context.Context, input string) ([]string, error) {
+	var locs []string
+	seen := map[string]bool{input: true}
+	queue := []string{input}
+	for len(queue) > 0 {
+		sitemap := queue[0]
+		queue = queue[1:]
+
+		doc, err := p.fetchSitemap(ctx, sitemap)
+		if err != nil {
+			if sitemap == input {
+				return nil, err
+			}
+			hclog.Default().Warn("skipping unreadable sitemap", "input", sitemap, "error", err)
+			continue
+		}
+
+		for _, s := range doc.Sitemaps {
+			loc := strings.TrimSpace(s.Loc)
+			if len(loc) <= 0 || seen[loc] {
+				continue
+			}
+			seen[loc] = true
+			queue = append(queue, loc)
+		}
+
+		for _, u := range doc.Urls {
+			locs = append(locs, u.Loc)
+		}
+	}
+
+	return p.filterLinks(locs, input), nil
+}
+
+func (p *Parser) fetchSitemap(ctx context.Context, input string) (sitemapDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	url, _, err := getUrl(input)
+	if err != nil {
+		return sitemapDocument{}, err
+	}
+
+	response, err := p.get(ctx, *url)
+	if err != nil {
+		return sitemapDocument{}, err
+	}
+	defer closeBody(response.Body)
+
+	if response.StatusCode != http.StatusOK {
+		return sitemapDocument{}, fmt.Errorf("unexpected status %s for sitemap %s", response.Status, input)
+	}
+
+	body := bufio.NewReader(response.Body)
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return sitemapDocument{}, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var doc sitemapDocument
+	if err := xml.NewDecoder(reader).Decode(&doc); err != nil {
+		return sitemapDocument{}, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const UnknownContentLength = -1
+
+type StatusCheck struct {
+	URL           string
+	Status        string
+	StatusCode    int
+	ContentLength int64
+	ContentType   string
+	Latency       time.Duration
+}
+
+func (p *Parser) CheckStatus(input string) (StatusCheck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	url, _, err := getUrl(input)
+	if err != nil {
+		return StatusCheck{}, err
+	}
+
+	check, err := p.head(ctx, input, url, p.checkStatusWithGet)
+	if err != nil {
+		return check, err
+	}
+
+	if check.ContentLength < 0 && p.opts.MeasureUnknownBodies && !p.opts.RequireContentLength {
+		return p.checkStatusWithGet(ctx, input, url)
+	}
+
+	return p.unknownContentLength(check)
+}
+
+func (p *Parser) CheckExternal(ctx context.Context, input string) (StatusCheck, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	url, _, err := getUrl(input)
+	if err != nil {
+		return StatusCheck{URL: input}, err
+	}
+
+	return p.head(ctx, input, url, p.rangedGet)
+}
+
+func (p *Parser) head(ctx context.Context, input string, url *url.URL, fallback func(context.Context, string, *url.URL) (StatusCheck, error)) (StatusCheck, error) {
+	response, err := p.fetchWithMethod(ctx, http.MethodHead, *url)
+	if err == nil {
+		closeBody(response.Body)
+	}
+
+	if err != nil || response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented {
+		return fallback(ctx, input, url)
+	}
+
+	return newStatusCheck(input, response), nil
+}
+
+func (p *Parser) rangedGet(ctx context.Context, input string, url *url.URL) (StatusCheck, error) {
+	response, err := p.fetchWithHeaders(ctx, http.MethodGet, *url, map[string]string{"Range": "bytes=0-0"})
+	if err != nil {
+		return StatusCheck{URL: input}, err
+	}
+	closeBody(response.Body)
+
+	check := newStatusCheck(input, response)
+	check.ContentLength = UnknownContentLength
+
+	if response.StatusCode == http.StatusPartialContent {
+		check.Status = "200 OK"
+		check.StatusCode = http.StatusOK
+	}
+
+	return check, nil
+}
+
+func (p *Parser) checkStatusWithGet(ctx context.Context, input string, url *url.URL) (StatusCheck, error) {
+	response, err := p.get(ctx, *url)
+	if err != nil {
+		return StatusCheck{URL: input}, err
+	}
+	defer closeBody(response.Body)
+
+	check := newStatusCheck(input, response)
+
+	if check.ContentLength >= 0 || !p.opts.MeasureUnknownBodies {
+		return p.unknownContentLength(check)
+	}
+
+	size, err := io.Copy(io.Discard, response.Body)
+	if err != nil {
+		return check, err
+	}
+
+	check.ContentLength = size
+	return check, nil
+}
+
+func newStatusCheck(input string, response SimpleHttpResponse) StatusCheck {
+	return StatusCheck{
+		URL:           input,
+		Status:        response.Status,
+		StatusCode:    response.StatusCode,
+		ContentLength: response.ContentLength,
+		ContentType:   response.Header.Get("Content-Type"),
+		Latency:       response.Latency,
+	}
+}
+
+func (p *Parser) unknownContentLength(check StatusCheck) (StatusCheck, error) {
+	if check.ContentLength >= 0 {
+		return check, nil
+	}
+
+	if p.opts.RequireContentLength {
+		return check, fmt.Errorf("missing content-length for input %s", check.URL)
+	}
+
+	check.ContentLength = UnknownContentLength
+	return check, nil
+}
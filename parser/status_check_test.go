@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStatusCheckServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sized" {
+			w.Header().Set("Content-Length", "5")
+			fmt.Fprint(w, "hello")
+			return
+		}
+
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "streamed body")
+	}))
+}
+
+func TestCheckStatusContentLength(t *testing.T) {
+	server := newStatusCheckServer()
+	defer server.Close()
+
+	check, err := getTestParser(ParserOptions{Timeout: time.Second}).CheckStatus(server.URL + "/sized")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.StatusCode != 200 {
+		t.Fatalf("expected: %d, actual: %d", 200, check.StatusCode)
+	}
+
+	if check.ContentLength != 5 {
+		t.Fatalf("expected: %d, actual: %d", 5, check.ContentLength)
+	}
+}
+
+func TestCheckStatusFallsBackToGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Length", "5")
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	check, err := getTestParser(ParserOptions{Timeout: time.Second}).CheckStatus(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.StatusCode != 200 || check.ContentLength != 5 || check.ContentType != "image/png" {
+		t.Fatalf("expected: %d %d %s, actual: %d %d %s", 200, 5, "image/png", check.StatusCode, check.ContentLength, check.ContentType)
+	}
+}
+
+func TestCheckStatusUnknownContentLength(t *testing.T) {
+	server := newStatusCheckServer()
+	defer server.Close()
+
+	check, err := getTestParser(ParserOptions{Timeout: time.Second}).CheckStatus(server.URL + "/streamed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.ContentLength != UnknownContentLength {
+		t.Fatalf("expected: %d, actual: %d", UnknownContentLength, check.ContentLength)
+	}
+}
+
+func TestCheckStatusMeasureUnknownBodies(t *testing.T) {
+	server := newStatusCheckServer()
+	defer server.Close()
+
+	check, err := getTestParser(ParserOptions{Timeout: time.Second, MeasureUnknownBodies: true}).CheckStatus(server.URL + "/streamed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.ContentLength != int64(len("streamed body")) {
+		t.Fatalf("expected: %d, actual: %d", len("streamed body"), check.ContentLength)
+	}
+}
+
+func TestCheckStatusRequireContentLength(t *testing.T) {
+	server := newStatusCheckServer()
+	defer server.Close()
+
+	check, err := getTestParser(ParserOptions{Timeout: time.Second, RequireContentLength: true}).CheckStatus(server.URL + "/streamed")
+	if err == nil || !strings.Contains(err.Error(), "missing content-length") {
+		t.Fatalf("expected missing content-length error, actual: %v", err)
+	}
+
+	if check.StatusCode != 200 {
+		t.Fatalf("expected: %d, actual: %d", 200, check.StatusCode)
+	}
+}
+
+func TestCheckExternal(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, "h")
+	}))
+	defer server.Close()
+
+	parser := getTestParser(ParserOptions{Timeout: time.Second})
+	check, err := parser.CheckExternal(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.StatusCode != http.StatusOK {
+		t.Fatalf("expected: %d, actual: %d", http.StatusOK, check.StatusCode)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=0-0" {
+		t.Fatalf("expected a ranged get, actual: %v", ranges)
+	}
+
+	check, err = parser.CheckExternal(context.Background(), server.URL+"/missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if check.StatusCode != http.StatusNotFound || len(ranges) != 1 {
+		t.Fatalf("expected: %d, actual: %d", http.StatusNotFound, check.StatusCode)
+	}
+}
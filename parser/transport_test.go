@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	transport, err := newTransport(ParserOptions{MaxIdleConnsPerHost: 256, MaxConnsPerHost: 8, IdleConnTimeout: time.Minute, DisableKeepAlives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transport.MaxIdleConnsPerHost != 256 || transport.MaxIdleConns != 256 || transport.MaxConnsPerHost != 8 {
+		t.Fatalf("expected: %d %d %d, actual: %d %d %d", 256, 256, 8, transport.MaxIdleConnsPerHost, transport.MaxIdleConns, transport.MaxConnsPerHost)
+	}
+
+	if transport.IdleConnTimeout != time.Minute || !transport.DisableKeepAlives {
+		t.Fatalf("expected: %s %v, actual: %s %v", time.Minute, true, transport.IdleConnTimeout, transport.DisableKeepAlives)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestParserClient(t *testing.T) {
+	var requested []string
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       io.NopCloser(strings.NewReader(`<a href="/about">about</a>`)),
+			Request:    req,
+		}, nil
+	})}
+
+	parser, err := NewParser(ParserOptions{Timeout: time.Second, SameSubdomain: true, Client: client})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := parser.ParseLinks("https://monzo.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"https://monzo.com/about"}
+	if !slices.Equal(output.Links, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, output.Links)
+	}
+
+	if !slices.Equal(requested, []string{"https://monzo.com"}) {
+		t.Fatalf("expected: %v, actual: %v", []string{"https://monzo.com"}, requested)
+	}
+
+	if client.CheckRedirect != nil {
+		t.Fatalf("expected the injected client to be left unchanged")
+	}
+}
+
+func BenchmarkParseLinksTransport(b *testing.B) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/about">about</a>`))
+	}))
+	defer server.Close()
+
+	const workers = 32
+	for _, bench := range []struct {
+		name string
+		opts ParserOptions
+	}{
+		{"default", ParserOptions{Timeout: 5 * time.Second}},
+		{"tuned", ParserOptions{Timeout: 5 * time.Second, MaxIdleConnsPerHost: workers}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			p, err := NewParser(bench.opts)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			tasks := make(chan bool)
+			var group sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				group.Add(1)
+				go func() {
+					defer group.Done()
+					for range tasks {
+						if _, err := p.ParseLinks(server.URL); err != nil {
+							b.Error(err)
+						}
+					}
+				}()
+			}
+
+			for i := 0; i < b.N; i++ {
+				tasks <- true
+			}
+			close(tasks)
+			group.Wait()
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import "container/heap"
+
+type queuedTask[T comparable] struct {
+	task     T
+	priority int
+	seq      uint64
+}
+
+type taskQueue[T comparable] struct {
+	priority func(T) int
+	items    []queuedTask[T]
+	seq      uint64
+}
+
+func (q *taskQueue[T]) push(t T) {
+	if q.priority == nil {
+		q.items = append(q.items, queuedTask[T]{task: t})
+		return
+	}
+
+	q.seq++
+	heap.Push(q, queuedTask[T]{task: t, priority: q.priority(t), seq: q.seq})
+}
+
+func (q *taskQueue[T]) pop() T {
+	if q.priority == nil {
+		t := q.items[0].task
+		q.items = q.items[1:]
+		return t
+	}
+
+	return heap.Pop(q).(queuedTask[T]).task
+}
+
+func (q *taskQueue[T]) Len() int {
+	return len(q.items)
+}
+
+func (q *taskQueue[T]) Less(i, j int) bool {
+	if q.items[i].priority != q.items[j].priority {
+		return q.items[i].priority > q.items[j].priority
+	}
+	return q.items[i].seq < q.items[j].seq
+}
+
+func (q *taskQueue[T]) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+}
+
+func (q *taskQueue[T]) Push(x any) {
+	q.items = append(q.items, x.(queuedTask[T]))
+}
+
+func (q *taskQueue[T]) Pop() any {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items = q.items[:last]
+	return item
+}
@@ -1,53 +1,155 @@
 package scheduler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/denis101/monzo-techtest/set"
 	"github.com/hashicorp/go-hclog"
 )
 
 type tuple = [2]interface{}
 
 type SchedulerOptions struct {
-	MaxWorkers  int
-	Interactive bool
+	MaxWorkers    int
+	Interactive   bool
+	ReportErrors  bool
+	Dedup         bool
+	MaxQueueSize  int
+	AutoTune      bool
+	MinWorkers    int
+	TargetLatency time.Duration
+	TuneWindow    int
+}
+
+var ErrQueueFull = errors.New("scheduler queue full")
+var ErrSchedulerClosed = errors.New("scheduler closed")
+
+type TaskError[T comparable] struct {
+	Task T
+	Err  error
+}
+
+func (e TaskError[T]) Error() string {
+	return fmt.Sprintf("task %v: %s", e.Task, e.Err)
+}
+
+func (e TaskError[T]) Unwrap() error {
+	return e.Err
 }
 
 type Scheduler[T comparable] struct {
 	WorkerState    chan tuple
+	Errors         chan error
 	workers        []worker[T]
+	workersLock    sync.Mutex
+	started        bool
 	workerPool     chan *worker[T]
-	quit           bool
-	handler        func(T)
-	inputQueue     []T
+	done           chan struct{}
+	wake           chan struct{}
+	stopOnce       sync.Once
+	workerGroup    sync.WaitGroup
+	runGroup       sync.WaitGroup
+	handler        func(T) error
+	inputQueue     taskQueue[T]
+	enqueued       set.Set[T]
 	inputQueueLock sync.Mutex
+	notFull        *sync.Cond
+	pending        int
 	opts           SchedulerOptions
+	tuner          *tuner
+	active         atomic.Int32
+	closed         atomic.Bool
 }
 
 func NewScheduler[T comparable](opts SchedulerOptions) *Scheduler[T] {
-	return &Scheduler[T]{
+	s := &Scheduler[T]{
 		WorkerState: make(chan tuple, opts.MaxWorkers),
-		workerPool:  make(chan *worker[T], opts.MaxWorkers),
+		Errors:      make(chan error, opts.MaxWorkers),
+		workerPool:  make(chan *worker[T]),
+		done:        make(chan struct{}),
+		wake:        make(chan struct{}, 1),
 		opts:        opts,
 	}
+
+	s.notFull = sync.NewCond(&s.inputQueueLock)
+
+	if opts.AutoTune {
+		s.tuner = newTuner(opts.MinWorkers, opts.MaxWorkers, opts.TargetLatency, opts.TuneWindow)
+	}
+
+	return s
 }
 
-func (s *Scheduler[T]) WithHandler(handler func(T)) *Scheduler[T] {
+func (s *Scheduler[T]) WithHandler(handler func(T) error) *Scheduler[T] {
 	s.handler = handler
 
+	s.workersLock.Lock()
+	defer s.workersLock.Unlock()
 	for i := 0; i < s.opts.MaxWorkers; i++ {
-		s.workers = append(s.workers,
-			newWorker(i,
-				s.handler,
-				s.opts.Interactive,
-				s.workerPool,
-				s.WorkerState))
+		s.workers = append(s.workers, s.newWorker(i))
 	}
 
 	return s
 }
 
+func (s *Scheduler[T]) WithPriority(priority func(T) int) *Scheduler[T] {
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+
+	queued := s.inputQueue.items
+	s.inputQueue = taskQueue[T]{priority: priority}
+	for _, q := range queued {
+		s.inputQueue.push(q.task)
+	}
+	return s
+}
+
+func (s *Scheduler[T]) newWorker(id int) worker[T] {
+	return newWorker(id,
+		s.handle,
+		s.opts.Interactive,
+		s.workerPool,
+		s.WorkerState,
+		s.done)
+}
+
+func (s *Scheduler[T]) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	s.workersLock.Lock()
+	defer s.workersLock.Unlock()
+
+	for len(s.workers) < n {
+		w := s.newWorker(len(s.workers))
+		if s.started {
+			s.workerGroup.Add(1)
+			w.start(&s.workerGroup)
+		}
+		s.workers = append(s.workers, w)
+	}
+
+	for len(s.workers) > n {
+		last := len(s.workers) - 1
+		close(s.workers[last].cha.quit)
+		s.workers = s.workers[:last]
+	}
+
+	hclog.Default().Debug("scheduler resized", "workers", n)
+}
+
+func (s *Scheduler[T]) Workers() int {
+	s.workersLock.Lock()
+	defer s.workersLock.Unlock()
+	return len(s.workers)
+}
+
 func (s *Scheduler[T]) Dispatch(tasks []T) {
 	for _, t := range tasks {
 		s.enqueue(t)
@@ -61,53 +163,231 @@ func (s *Scheduler[T]) Start() {
 		panic(err)
 	}
 
+	s.workersLock.Lock()
+	s.started = true
 	for _, w := range s.workers {
-		w.start()
+		s.workerGroup.Add(1)
+		w.start(&s.workerGroup)
 	}
+	s.workersLock.Unlock()
 
-	go s.run()
+	s.runGroup.Add(1)
+	go func() {
+		defer s.runGroup.Done()
+		s.run()
+	}()
 }
 
 func (s *Scheduler[T]) Stop() {
-	s.quit = true
+	s.close()
+	s.workerGroup.Wait()
+	s.runGroup.Wait()
+}
 
-	var wg sync.WaitGroup
-	for _, w := range s.workers {
-		wg.Add(1)
-		go func(w worker[T]) {
-			defer wg.Done()
-			w.cha.quit <- true
-		}(w)
+func (s *Scheduler[T]) close() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+	})
+
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+	s.notFull.Broadcast()
+}
+
+func (s *Scheduler[T]) Shutdown(ctx context.Context) error {
+	s.closed.Store(true)
+	s.inputQueueLock.Lock()
+	s.notFull.Broadcast()
+	s.inputQueueLock.Unlock()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !s.Idle() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.close()
+			return ctx.Err()
+		}
 	}
 
-	wg.Wait()
+	s.Stop()
+	return nil
 }
 
 func (s *Scheduler[T]) run() {
 	for {
-		if s.quit {
+		select {
+		case <-s.done:
 			return
+		default:
 		}
 
-		if len(s.inputQueue) <= 0 {
+		if s.queueSize() <= 0 {
+			select {
+			case <-s.wake:
+			case <-s.done:
+				return
+			}
+			continue
+		}
+
+		if s.tuner != nil && int(s.active.Load()) >= s.tuner.current() {
+			select {
+			case <-s.wake:
+			case <-s.done:
+				return
+			}
 			continue
 		}
 
 		t := s.dequeue()
-		worker := <-s.workerPool
+
+		var worker *worker[T]
+		select {
+		case worker = <-s.workerPool:
+		case <-s.done:
+			return
+		}
+
 		hclog.Default().Trace("scheduler got worker", "id", worker.id)
-		worker.cha.tasks <- t
+		s.active.Add(1)
+		s.assigned()
+
+		select {
+		case worker.cha.tasks <- t:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Scheduler[T]) handle(t T) {
+	if err := s.handler(t); err != nil && s.opts.ReportErrors {
+		select {
+		case s.Errors <- TaskError[T]{Task: t, Err: err}:
+		case <-s.done:
+		}
+	}
+	s.active.Add(-1)
+	s.signal()
+}
+
+func (s *Scheduler[T]) Report(latency time.Duration, failed bool) {
+	if s.tuner != nil {
+		s.tuner.observe(latency, failed)
 	}
 }
 
 func (s *Scheduler[T]) enqueue(t T) {
-	s.inputQueue = append(s.inputQueue, t)
+	if s.closed.Load() {
+		return
+	}
+	if s.opts.Dedup && s.enqueued.Has(t) {
+		return
+	}
+
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+	for s.full() {
+		if s.stopped() {
+			return
+		}
+		s.notFull.Wait()
+	}
+
+	if s.opts.Dedup && !s.enqueued.TryAdd(t) {
+		return
+	}
+	s.inputQueue.push(t)
+	s.signal()
+}
+
+func (s *Scheduler[T]) TryDispatch(tasks []T) error {
+	if s.closed.Load() {
+		return ErrSchedulerClosed
+	}
+
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+
+	var batch set.Set[T]
+	var accepted []T
+	for _, t := range tasks {
+		if !batch.TryAdd(t) || (s.opts.Dedup && s.enqueued.Has(t)) {
+			continue
+		}
+		accepted = append(accepted, t)
+	}
+
+	if s.opts.MaxQueueSize > 0 && s.inputQueue.Len()+len(accepted) > s.opts.MaxQueueSize {
+		return ErrQueueFull
+	}
+
+	if s.opts.Dedup {
+		s.enqueued.AddSlice(accepted)
+	}
+	for _, t := range accepted {
+		s.inputQueue.push(t)
+	}
+	if len(accepted) > 0 {
+		s.signal()
+	}
+	return nil
+}
+
+func (s *Scheduler[T]) full() bool {
+	return s.opts.MaxQueueSize > 0 && s.inputQueue.Len() >= s.opts.MaxQueueSize
+}
+
+func (s *Scheduler[T]) stopped() bool {
+	if s.closed.Load() {
+		return true
+	}
+
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scheduler[T]) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
 }
 
 func (s *Scheduler[T]) dequeue() T {
 	s.inputQueueLock.Lock()
 	defer s.inputQueueLock.Unlock()
-	t := s.inputQueue[0]
-	s.inputQueue = s.inputQueue[1:]
+	t := s.inputQueue.pop()
+	s.pending++
+	s.notFull.Signal()
 	return t
 }
+
+func (s *Scheduler[T]) assigned() {
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+	s.pending--
+}
+
+func (s *Scheduler[T]) queueSize() int {
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+	return s.inputQueue.Len()
+}
+
+func (s *Scheduler[T]) Active() int {
+	return int(s.active.Load())
+}
+
+func (s *Scheduler[T]) Idle() bool {
+	s.inputQueueLock.Lock()
+	defer s.inputQueueLock.Unlock()
+	return s.pending <= 0 && s.active.Load() <= 0 && s.inputQueue.Len() <= 0
+}
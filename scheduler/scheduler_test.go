@@ -0,0 +1,457 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSchedulerStopBeforeStart(t *testing.T) {
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 2}).WithHandler(func(i int) error { return nil })
+	s.Stop()
+	s.Stop()
+}
+
+func TestSchedulerIdle(t *testing.T) {
+	release := make(chan bool)
+	handled := make(chan int, 2)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1}).WithHandler(func(i int) error {
+		<-release
+		handled <- i
+		return nil
+	})
+
+	if !s.Idle() {
+		t.Fatal("expected new scheduler to be idle")
+	}
+
+	s.Dispatch([]int{1, 2})
+	if s.Idle() {
+		t.Fatal("expected scheduler with queued tasks not to be idle")
+	}
+
+	s.Start()
+	defer s.Stop()
+	defer close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for s.Active() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected active: %d, actual: %d", 1, s.Active())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if s.Idle() {
+		t.Fatal("expected scheduler with an active task not to be idle")
+	}
+
+	release <- true
+	release <- true
+	<-handled
+	<-handled
+
+	deadline = time.Now().Add(time.Second)
+	for !s.Idle() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected scheduler to become idle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerConcurrentDispatch(t *testing.T) {
+	const producers = 8
+	const tasksPerProducer = 100
+
+	var lock sync.Mutex
+	handled := make(map[int]bool)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 4}).WithHandler(func(i int) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled[i] = true
+		return nil
+	})
+	s.Start()
+	defer s.Stop()
+
+	var group sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		group.Add(1)
+		go func(p int) {
+			defer group.Done()
+			for i := 0; i < tasksPerProducer; i++ {
+				s.Dispatch([]int{p*tasksPerProducer + i})
+			}
+		}(p)
+	}
+	group.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !s.Idle() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected scheduler to drain the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(handled) != producers*tasksPerProducer {
+		t.Fatalf("expected: %d, actual: %d", producers*tasksPerProducer, len(handled))
+	}
+}
+
+func TestSchedulerIdleDoesNotSpin(t *testing.T) {
+	handled := make(chan int, 1)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1}).WithHandler(func(i int) error {
+		handled <- i
+		return nil
+	})
+	s.Start()
+	defer s.Stop()
+
+	before := cpuTime(t)
+	time.Sleep(300 * time.Millisecond)
+	if used := cpuTime(t) - before; used > 100*time.Millisecond {
+		t.Fatalf("expected less than %s of cpu while idle, actual: %s", 100*time.Millisecond, used)
+	}
+
+	s.Dispatch([]int{1})
+	select {
+	case i := <-handled:
+		if i != 1 {
+			t.Fatalf("expected: %d, actual: %d", 1, i)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the dispatched task to be handled")
+	}
+}
+
+func cpuTime(t *testing.T) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		t.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+func TestSchedulerShutdownWaitsForTasks(t *testing.T) {
+	var handled atomic.Int32
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 2}).WithHandler(func(i int) error {
+		time.Sleep(50 * time.Millisecond)
+		handled.Add(1)
+		return nil
+	})
+	s.Dispatch([]int{1, 2, 3, 4, 5})
+	s.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if handled.Load() != 5 {
+		t.Fatalf("expected: %d, actual: %d", 5, handled.Load())
+	}
+
+	s.Dispatch([]int{6})
+	if !s.Idle() {
+		t.Fatal("expected scheduler to reject tasks after shutdown")
+	}
+}
+
+func TestSchedulerShutdownTimeout(t *testing.T) {
+	release := make(chan bool)
+	defer close(release)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1}).WithHandler(func(i int) error {
+		<-release
+		return nil
+	})
+	s.Dispatch([]int{1})
+	s.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected: %v, actual: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSchedulerReportsErrors(t *testing.T) {
+	failure := errors.New("failed")
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1, ReportErrors: true}).WithHandler(func(i int) error {
+		if i == 2 {
+			return failure
+		}
+		return nil
+	})
+	s.Dispatch([]int{1, 2})
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case err := <-s.Errors:
+		var taskErr TaskError[int]
+		if !errors.As(err, &taskErr) {
+			t.Fatalf("expected: %T, actual: %T", taskErr, err)
+		}
+		if taskErr.Task != 2 {
+			t.Fatalf("expected: %d, actual: %d", 2, taskErr.Task)
+		}
+		if !errors.Is(err, failure) {
+			t.Fatalf("expected: %v, actual: %v", failure, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an error on the errors channel")
+	}
+}
+
+func TestSchedulerResize(t *testing.T) {
+	var running, peak atomic.Int32
+	var handled atomic.Int32
+	release := make(chan bool)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1}).WithHandler(func(i int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		handled.Add(1)
+		return nil
+	})
+	s.Start()
+	defer s.Stop()
+
+	s.Resize(4)
+	if s.Workers() != 4 {
+		t.Fatalf("expected: %d, actual: %d", 4, s.Workers())
+	}
+
+	s.Dispatch([]int{1, 2, 3, 4})
+	waitFor(t, func() bool { return running.Load() == 4 })
+	for i := 0; i < 4; i++ {
+		release <- true
+	}
+	waitFor(t, func() bool { return s.Idle() })
+
+	s.Resize(1)
+	if s.Workers() != 1 {
+		t.Fatalf("expected: %d, actual: %d", 1, s.Workers())
+	}
+
+	peak.Store(0)
+	s.Dispatch([]int{5, 6, 7})
+	for i := 0; i < 3; i++ {
+		release <- true
+	}
+	waitFor(t, func() bool { return s.Idle() })
+
+	if handled.Load() != 7 {
+		t.Fatalf("expected: %d, actual: %d", 7, handled.Load())
+	}
+
+	if peak.Load() != 1 {
+		t.Fatalf("expected: %d, actual: %d", 1, peak.Load())
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerDedup(t *testing.T) {
+	var handled atomic.Int32
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 2, Dedup: true}).WithHandler(func(i int) error {
+		handled.Add(1)
+		return nil
+	})
+	s.Dispatch([]int{1, 1, 2})
+	s.Start()
+	defer s.Stop()
+
+	s.Dispatch([]int{1, 2, 1})
+	waitFor(t, func() bool { return s.Idle() })
+	s.Dispatch([]int{1})
+	waitFor(t, func() bool { return s.Idle() })
+
+	if handled.Load() != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, handled.Load())
+	}
+}
+
+func TestSchedulerMaxQueueSize(t *testing.T) {
+	var lock sync.Mutex
+	handled := make(map[int]bool)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1, MaxQueueSize: 2}).WithHandler(func(i int) error {
+		lock.Lock()
+		defer lock.Unlock()
+		handled[i] = true
+		return nil
+	})
+
+	dispatched := make(chan bool)
+	go func() {
+		for i := 0; i < 20; i++ {
+			s.Dispatch([]int{i})
+		}
+		close(dispatched)
+	}()
+
+	waitFor(t, func() bool { return s.queueSize() == 2 })
+	s.Start()
+	defer s.Stop()
+
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("expected blocked producer to be released")
+	}
+	waitFor(t, func() bool { return s.Idle() })
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(handled) != 20 {
+		t.Fatalf("expected: %d, actual: %d", 20, len(handled))
+	}
+}
+
+func TestSchedulerTryDispatch(t *testing.T) {
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1, MaxQueueSize: 2}).WithHandler(func(i int) error {
+		return nil
+	})
+
+	if err := s.TryDispatch([]int{1, 2, 3}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected: %v, actual: %v", ErrQueueFull, err)
+	}
+
+	if err := s.TryDispatch([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.queueSize() != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, s.queueSize())
+	}
+
+	if err := s.TryDispatch([]int{3}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected: %v, actual: %v", ErrQueueFull, err)
+	}
+}
+
+func TestSchedulerStopReleasesBlockedDispatch(t *testing.T) {
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1, MaxQueueSize: 1}).WithHandler(func(i int) error {
+		return nil
+	})
+	s.Dispatch([]int{1})
+
+	dispatched := make(chan bool)
+	go func() {
+		s.Dispatch([]int{2})
+		close(dispatched)
+	}()
+
+	s.Stop()
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("expected stop to release blocked producer")
+	}
+}
+
+func TestSchedulerDedupIgnoresRejectedDispatch(t *testing.T) {
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1, MaxQueueSize: 1, Dedup: true}).WithHandler(func(i int) error {
+		return nil
+	})
+	s.Dispatch([]int{1})
+
+	dispatched := make(chan bool)
+	go func() {
+		s.Dispatch([]int{2})
+		close(dispatched)
+	}()
+
+	waitFor(t, func() bool { return s.queueSize() == 1 })
+	s.Stop()
+	<-dispatched
+
+	if s.enqueued.Has(2) {
+		t.Fatalf("expected: %v, actual: %v", false, true)
+	}
+
+	if !s.enqueued.Has(1) {
+		t.Fatalf("expected: %v, actual: %v", true, false)
+	}
+}
+
+func TestSchedulerStopDoesNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	var handled atomic.Int32
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 4, Interactive: true}).WithHandler(func(i int) error {
+		handled.Add(1)
+		return nil
+	})
+	s.Start()
+	s.Dispatch([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	waitFor(t, func() bool { return handled.Load() >= 4 })
+	s.Resize(2)
+	s.Stop()
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("expected goroutines: %d, actual: %d", before, after)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	handled := make(chan int, 6)
+	s := NewScheduler[int](SchedulerOptions{MaxWorkers: 1}).WithHandler(func(i int) error {
+		handled <- i
+		return nil
+	}).WithPriority(func(i int) int {
+		return i / 10
+	})
+	s.Dispatch([]int{1, 30, 2, 20, 31, 3})
+	s.Start()
+	defer s.Stop()
+
+	expected := []int{30, 31, 20, 1, 2, 3}
+	for _, e := range expected {
+		select {
+		case i := <-handled:
+			if i != e {
+				t.Fatalf("expected: %d, actual: %d", e, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected all tasks to be handled")
+		}
+	}
+}
+
+func TestSchedulerFifoByDefault(t *testing.T) {
+	var s Scheduler[int]
+	s.inputQueue.push(3)
+	s.inputQueue.push(1)
+	s.inputQueue.push(2)
+
+	for _, e := range []int{3, 1, 2} {
+		if i := s.inputQueue.pop(); i != e {
+			t.Fatalf("expected: %d, actual: %d", e, i)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const DefaultTuneWindow = 10
+const DefaultTuneErrorRate = 0.1
+
+type tuner struct {
+	lock       sync.Mutex
+	limit      int
+	minWorkers int
+	maxWorkers int
+	target     time.Duration
+	window     int
+	samples    []time.Duration
+	errors     int
+}
+
+func newTuner(minWorkers int, maxWorkers int, target time.Duration, window int) *tuner {
+	if minWorkers <= 0 {
+		minWorkers = 1
+	}
+
+	if minWorkers > maxWorkers {
+		minWorkers = maxWorkers
+	}
+
+	if window <= 0 {
+		window = DefaultTuneWindow
+	}
+
+	return &tuner{
+		limit:      minWorkers,
+		minWorkers: minWorkers,
+		maxWorkers: maxWorkers,
+		target:     target,
+		window:     window,
+	}
+}
+
+func (t *tuner) observe(latency time.Duration, failed bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.samples = append(t.samples, latency)
+	if failed {
+		t.errors++
+	}
+
+	if len(t.samples) < t.window {
+		return
+	}
+
+	var total time.Duration
+	for _, s := range t.samples {
+		total += s
+	}
+
+	average := total / time.Duration(len(t.samples))
+	errorRate := float64(t.errors) / float64(len(t.samples))
+	t.samples = t.samples[:0]
+	t.errors = 0
+
+	if average > t.target || errorRate > DefaultTuneErrorRate {
+		t.limit = max(t.minWorkers, t.limit/2)
+	} else if t.limit < t.maxWorkers {
+		t.limit++
+	}
+
+	hclog.Default().Trace("tuner adjusted concurrency",
+		"average", average,
+		"target", t.target,
+		"errorRate", errorRate,
+		"limit", t.limit,
+	)
+}
+
+func (t *tuner) current() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.limit
+}
@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTunerStartsAtMinWorkers(t *testing.T) {
+	tuner := newTuner(2, 8, time.Millisecond*100, 1)
+	if tuner.current() != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, tuner.current())
+	}
+}
+
+func TestTunerIncreasesWhileLatencyLow(t *testing.T) {
+	tuner := newTuner(1, 4, time.Millisecond*100, 2)
+	for i := 0; i < 20; i++ {
+		tuner.observe(time.Millisecond*10, false)
+	}
+
+	if tuner.current() != 4 {
+		t.Fatalf("expected: %d, actual: %d", 4, tuner.current())
+	}
+}
+
+func TestTunerBacksOffWhenLatencyHigh(t *testing.T) {
+	tuner := newTuner(1, 8, time.Millisecond*100, 1)
+	for i := 0; i < 7; i++ {
+		tuner.observe(time.Millisecond*10, false)
+	}
+
+	if tuner.current() != 8 {
+		t.Fatalf("expected: %d, actual: %d", 8, tuner.current())
+	}
+
+	tuner.observe(time.Second, false)
+	if tuner.current() != 4 {
+		t.Fatalf("expected: %d, actual: %d", 4, tuner.current())
+	}
+
+	for i := 0; i < 10; i++ {
+		tuner.observe(time.Second, false)
+	}
+
+	if tuner.current() != 1 {
+		t.Fatalf("expected: %d, actual: %d", 1, tuner.current())
+	}
+}
+
+func TestTunerWaitsForFullWindow(t *testing.T) {
+	tuner := newTuner(1, 8, time.Millisecond*100, 3)
+	tuner.observe(time.Millisecond, false)
+	tuner.observe(time.Millisecond, false)
+	if tuner.current() != 1 {
+		t.Fatalf("expected: %d, actual: %d", 1, tuner.current())
+	}
+
+	tuner.observe(time.Millisecond, false)
+	if tuner.current() != 2 {
+		t.Fatalf("expected: %d, actual: %d", 2, tuner.current())
+	}
+}
+
+func TestTunerBacksOffWhenErrorsRise(t *testing.T) {
+	tuner := newTuner(1, 8, time.Millisecond*100, 4)
+	for i := 0; i < 28; i++ {
+		tuner.observe(time.Millisecond*10, false)
+	}
+
+	if tuner.current() != 8 {
+		t.Fatalf("expected: %d, actual: %d", 8, tuner.current())
+	}
+
+	for i := 0; i < 4; i++ {
+		tuner.observe(time.Millisecond*10, i == 0)
+	}
+
+	if tuner.current() != 4 {
+		t.Fatalf("expected: %d, actual: %d", 4, tuner.current())
+	}
+}
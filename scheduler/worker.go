@@ -1,6 +1,8 @@
 package scheduler
 
 import (
+	"sync"
+
 	"github.com/hashicorp/go-hclog"
 )
 
@@ -15,7 +17,8 @@ type workerChannels[T comparable] struct {
 	pool  chan *worker[T]
 	state chan tuple
 	tasks chan T
-	quit  chan bool
+	quit  chan struct{}
+	done  <-chan struct{}
 }
 
 func newWorker[T comparable](
@@ -23,7 +26,8 @@ func newWorker[T comparable](
 	handler func(T),
 	reportState bool,
 	pool chan *worker[T],
-	state chan tuple) worker[T] {
+	state chan tuple,
+	done <-chan struct{}) worker[T] {
 	return worker[T]{
 		id:          id,
 		handler:     handler,
@@ -32,28 +36,39 @@ func newWorker[T comparable](
 			pool:  pool,
 			state: state,
 			tasks: make(chan T),
-			quit:  make(chan bool),
+			quit:  make(chan struct{}),
+			done:  done,
 		},
 	}
 }
 
-func (w worker[T]) start() {
+func (w worker[T]) start(group *sync.WaitGroup) {
 	go func() {
+		defer group.Done()
+
 		for {
 			hclog.Default().Trace("worker waiting", "id", w.id)
-			w.cha.pool <- &w
+			select {
+			case w.cha.pool <- &w:
+			case <-w.cha.quit:
+				return
+			case <-w.cha.done:
+				return
+			}
 
 			select {
 			case task := <-w.cha.tasks:
 				hclog.Default().Trace("worker start task", "id", w.id)
 				if w.reportState {
-					w.cha.state <- tuple{w.id, task}
+					select {
+					case w.cha.state <- tuple{w.id, task}:
+					case <-w.cha.done:
+					}
 				}
 
 				w.handler(task)
 				hclog.Default().Trace("worker end task", "id", w.id)
-			case <-w.cha.quit:
-				close(w.cha.tasks)
+			case <-w.cha.done:
 				return
 			}
 		}
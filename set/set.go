@@ -0,0 +1,69 @@
+package set
+
+import "sync"
+
+type Set[T comparable] struct {
+	data map[T]struct{}
+	lock sync.RWMutex
+}
+
+func (s *Set[T]) Add(t T) *Set[T] {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.data == nil {
+		s.data = make(map[T]struct{})
+	}
+	s.data[t] = struct{}{}
+	return s
+}
+
+func (s *Set[T]) TryAdd(t T) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.data == nil {
+		s.data = make(map[T]struct{})
+	}
+	if _, ok := s.data[t]; ok {
+		return false
+	}
+	s.data[t] = struct{}{}
+	return true
+}
+
+func (s *Set[T]) AddSlice(a []T) *Set[T] {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.data == nil {
+		s.data = make(map[T]struct{})
+	}
+
+	for _, t := range a {
+		s.data[t] = struct{}{}
+	}
+	return s
+}
+
+func (s *Set[T]) Has(element T) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok := s.data[element]
+	return ok
+}
+
+func (s *Set[T]) Slice() []T {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	result := make([]T, len(s.data))
+	i := 0
+	for k := range s.data {
+		result[i] = k
+		i++
+	}
+	return result
+}
+
+func (s *Set[T]) Size() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.data)
+}
@@ -0,0 +1,49 @@
+package set
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSetString(t *testing.T) {
+	var s Set[string]
+	s.Add("a").AddSlice([]string{"b", "a", "c"})
+
+	if s.Size() != 3 {
+		t.Fatalf("expected: %d, actual: %d", 3, s.Size())
+	}
+
+	if !s.Has("b") || s.Has("d") {
+		t.Fatalf("expected: %v %v, actual: %v %v", true, false, s.Has("b"), s.Has("d"))
+	}
+
+	if s.TryAdd("a") || !s.TryAdd("d") {
+		t.Fatal("expected TryAdd to only add missing elements")
+	}
+
+	values := s.Slice()
+	slices.Sort(values)
+	expected := []string{"a", "b", "c", "d"}
+	if !slices.Equal(values, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, values)
+	}
+}
+
+func TestSetInt(t *testing.T) {
+	var s Set[int]
+	if s.Size() != 0 || s.Has(1) || len(s.Slice()) != 0 {
+		t.Fatal("expected empty set")
+	}
+
+	s.AddSlice([]int{3, 1, 2, 1}).Add(3)
+	if s.Size() != 3 {
+		t.Fatalf("expected: %d, actual: %d", 3, s.Size())
+	}
+
+	values := s.Slice()
+	slices.Sort(values)
+	expected := []int{1, 2, 3}
+	if !slices.Equal(values, expected) {
+		t.Fatalf("expected: %v, actual: %v", expected, values)
+	}
+}